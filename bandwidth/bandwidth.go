@@ -0,0 +1,198 @@
+package bandwidth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chunkSize is the most bytes read before waiting for the limiter, it keeps
+// large reads from bursting far past the limit
+const chunkSize = 32 * 1024
+
+// HTTPClient is the interface the aws sdk uses to send requests
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// units are the multipliers of the units a rate can be given in
+var units = map[string]float64{
+	"":    1,
+	"B":   1,
+	"K":   1024,
+	"KB":  1000,
+	"KIB": 1024,
+	"M":   1024 * 1024,
+	"MB":  1000 * 1000,
+	"MIB": 1024 * 1024,
+	"G":   1024 * 1024 * 1024,
+	"GB":  1000 * 1000 * 1000,
+	"GIB": 1024 * 1024 * 1024,
+}
+
+// ParseRate parses a rate in bytes per second such as 500MiB/s, 1.5GB or
+// 1048576. K, M and G on their own are binary units.
+func ParseRate(s string) (float64, error) {
+	trimmed := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "/S")
+	split := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if split < 0 {
+		split = len(trimmed)
+	}
+
+	n, err := strconv.ParseFloat(trimmed[:split], 64)
+	unit, ok := units[strings.TrimSpace(trimmed[split:])]
+	if err != nil || !ok || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %s, expected a number of bytes per second like 500MiB/s", s)
+	}
+
+	return n * unit, nil
+}
+
+// Limiter is a token bucket shared by every reader it limits, so together
+// they transfer at most rate bytes per second. All methods are safe to call
+// on a nil Limiter, which limits nothing.
+type Limiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+// NewLimiter creates a Limiter of rate bytes per second. The bucket holds a
+// tenth of a second of tokens, and at least one chunk, so that waking up late
+// from a wait doesn't throw away tokens at high rates.
+func NewLimiter(rate float64) *Limiter {
+	burst := math.Max(chunkSize, rate/10)
+	return &Limiter{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes n tokens from the bucket, going into debt if there are not
+// enough, and returns how long to wait until the debt is paid off
+func (l *Limiter) reserve(n int) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Wait blocks until n bytes may be transferred or ctx is cancelled
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	delay := l.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedReader waits for the limiter after each read from an io.Reader
+type limitedReader struct {
+	io.Reader
+	ctx     context.Context
+	limiter *Limiter
+}
+
+// Read reads at most chunkSize bytes from the underlying reader and waits for them to be allowed
+func (r limitedReader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+
+	n, err := r.Reader.Read(p)
+	if waitErr := r.limiter.Wait(r.ctx, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+// Reader limits the rate bytes are read from r until ctx is cancelled
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+
+	return limitedReader{Reader: r, ctx: ctx, limiter: l}
+}
+
+// limitedReadCloser limits the rate bytes are read from an io.ReadCloser
+type limitedReadCloser struct {
+	limitedReader
+	closer io.Closer
+}
+
+// Close closes the underlying io.ReadCloser
+func (r limitedReadCloser) Close() error {
+	return r.closer.Close()
+}
+
+func (l *Limiter) readCloser(ctx context.Context, r io.ReadCloser) io.ReadCloser {
+	return limitedReadCloser{
+		limitedReader: limitedReader{Reader: r, ctx: ctx, limiter: l},
+		closer:        r,
+	}
+}
+
+// limitedHTTPClient limits the rate of request and response bodies
+type limitedHTTPClient struct {
+	client  HTTPClient
+	limiter *Limiter
+}
+
+// Do sends a request, limiting the rate its request and response bodies are transferred
+func (c limitedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = c.limiter.readCloser(req.Context(), req.Body)
+	}
+
+	resp, err := c.client.Do(req)
+	if err == nil && resp.Body != nil {
+		resp.Body = c.limiter.readCloser(req.Context(), resp.Body)
+	}
+
+	return resp, err
+}
+
+// LimitBodies wraps an HTTPClient to limit the rate of the bodies it sends and receives
+func (l *Limiter) LimitBodies(client HTTPClient) HTTPClient {
+	if l == nil {
+		return client
+	}
+
+	return limitedHTTPClient{client: client, limiter: l}
+}
@@ -0,0 +1,116 @@
+package bandwidth
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	cases := map[string]float64{
+		"1048576":  1048576,
+		"500MiB/s": 500 * 1024 * 1024,
+		"1.5GB":    1.5 * 1000 * 1000 * 1000,
+		"64k":      64 * 1024,
+		"10 MB/s":  10 * 1000 * 1000,
+		"100B/s":   100,
+	}
+
+	for s, expected := range cases {
+		actual, err := ParseRate(s)
+		if err != nil {
+			t.Errorf("encountered error while parsing rate %s: %s", s, err)
+		} else if actual != expected {
+			t.Errorf("expected ParseRate(%s) to equal %f but it was %f", s, expected, actual)
+		}
+	}
+
+	for _, invalid := range []string{"", "fast", "10XB", "0", "-5MB"} {
+		if _, err := ParseRate(invalid); err == nil {
+			t.Errorf("expected rate %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestLimiterSharedAcrossReaders(t *testing.T) {
+	limiter := NewLimiter(256 * 1024)
+	data := make([]byte, 64*1024)
+
+	start := time.Now()
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := io.Copy(ioutil.Discard, limiter.Reader(context.Background(), bytes.NewReader(data)))
+			done <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("encountered error while reading %s", err)
+		}
+	}
+
+	// 128 KiB at 256 KiB/s less the initial 32 KiB burst takes at least 375ms
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond {
+		t.Errorf("expected reads to be limited but they took %s", elapsed)
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	limiter := NewLimiter(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := io.Copy(ioutil.Discard, limiter.Reader(ctx, bytes.NewReader(make([]byte, 2*chunkSize))))
+	if err != context.Canceled {
+		t.Errorf("expected a cancelled read to fail with %s but got %v", context.Canceled, err)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var limiter *Limiter
+	r := bytes.NewReader(nil)
+	if limiter.Reader(context.Background(), r) != r {
+		t.Errorf("expected a nil limiter not to wrap readers")
+	}
+}
+
+// zeroReader reads an endless stream of zeros
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestLimiterThroughput(t *testing.T) {
+	const rate = 1024 * 1024 * 1024
+	const size = 256 * 1024 * 1024
+	limiter := NewLimiter(rate)
+	burst := limiter.burst
+
+	start := time.Now()
+	done := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			r := limiter.Reader(context.Background(), io.LimitReader(zeroReader{}, size/4))
+			_, err := io.Copy(ioutil.Discard, r)
+			done <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Fatalf("encountered error while reading %s", err)
+		}
+	}
+	elapsed := time.Since(start).Seconds()
+
+	// Past the initial burst the reads should take close to the full rate
+	//   without going over it
+	achieved := (size - burst) / elapsed
+	if achieved > rate*1.05 || achieved < rate*0.8 {
+		t.Errorf("expected reads to be limited to about %d B/s but they reached %.0f B/s", rate, achieved)
+	}
+}
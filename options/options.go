@@ -3,35 +3,151 @@ package options
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path"
 	"runtime"
+	"strconv"
+	"strings"
 
 	"github.com/jessevdk/go-flags"
 )
 
+// minPartSize and maxPartSize are the smallest and largest part sizes s3
+// allows, except for the last part
+const (
+	minPartSize int64 = 1024 * 1024 * 5
+	maxPartSize int64 = 1024 * 1024 * 1024 * 5
+)
+
 // Options - the options passed to the executable
 type Options struct {
-	PartSize              int64  `short:"p" long:"part-size" description:"Part size in bytes of parts to be downloaded"`
-	Concurrency           int    `short:"c" long:"concurrency" description:"Download concurrency"`
-	BufferSize            int    `short:"b" long:"buffer-size" description:"Size of download buffer in bytes"`
-	Checksum              bool   `long:"checksum" description:"Compare checksum if downloading or place checksum in metadata if uploading"`
-	Recursive             bool   `short:"r" long:"recursive" description:"Copy directories or folders recursively"`
-	Version               bool   `long:"version" description:"Print the current version"`
-	S3Url                 string `long:"s3_url" description:"A custom s3 API url (also available as an environment variable 'S3PARCP_S3_URL', the flag takes precedence)"`
-	MaxRetries            int    `long:"max-retries" description:"Max per chunk retries" default:"3"`
-	DisableSSL            bool   `long:"disable-ssl" description:"Disable SSL"`
-	FileCachedCredentials bool   `long:"file-cached-credentials" description:"Cache AWS credentials to the file system"`
-	Verbose               bool   `short:"v" long:"verbose" description:"verbose logging"`
+	PartSize              int64              `short:"p" long:"part-size" description:"Part size in bytes of parts to be uploaded or downloaded (defaults to a size picked from each file or object's size)"`
+	Concurrency           int                `short:"c" long:"concurrency" description:"Number of part requests, and part buffers with --buffer-size, in flight across all files or objects"`
+	BufferSize            int                `short:"b" long:"buffer-size" description:"Size of download buffer in bytes"`
+	Checksum              bool               `long:"checksum" description:"Compare checksum if downloading or place checksum in metadata if uploading"`
+	AddMetadata           func(string) error `long:"metadata" description:"Add key=value user metadata to uploaded or copied objects (can be repeated)"`
+	Metadata              map[string]string  `no-flag:"true"`
+	AddTag                func(string) error `long:"tag" description:"Add a key=value tag to uploaded or copied objects (can be repeated)"`
+	Tags                  map[string]string  `no-flag:"true"`
+	ContentType           string             `long:"content-type" description:"Content-Type of uploaded or copied objects (defaults to the type of the file's extension, or the source object's type when copying)"`
+	ContentEncoding       string             `long:"content-encoding" description:"Content-Encoding of uploaded or copied objects"`
+	ContentDisposition    string             `long:"content-disposition" description:"Content-Disposition of uploaded or copied objects"`
+	CacheControl          string             `long:"cache-control" description:"Cache-Control of uploaded or copied objects"`
+	StorageClass          string             `long:"storage-class" description:"Storage class of uploaded or copied objects (defaults to the bucket's default)"`
+	AddStorageClassRule   func(string) error `long:"storage-class-over" description:"Use a storage class for objects of at least this many bytes, as size=class (can be repeated, the largest size an object reaches wins)"`
+	StorageClassRules     []StorageClassRule `no-flag:"true"`
+	SSE                   string             `long:"sse" choice:"AES256" choice:"aws:kms" description:"Server-side encryption of uploaded or copied objects"`
+	SSEKMSKeyID           string             `long:"sse-kms-key-id" description:"KMS key ID to encrypt uploaded or copied objects with (implies --sse aws:kms)"`
+	SSECKeyFile           string             `long:"sse-c-key-file" description:"File holding a raw 256 bit key to encrypt uploaded or copied objects with and to decrypt downloaded or copied objects with (SSE-C)"`
+	Preserve              bool               `long:"preserve" description:"Preserve mode, ownership and modification time, storing them in metadata when uploading and restoring them when downloading"`
+	Progress              bool               `long:"progress" description:"Report progress, throughput and ETA while copying"`
+	Ranges                []string           `long:"range" description:"Only download this start-end range of bytes, end is inclusive and can be left out to download to the end (can be repeated)"`
+	Sparse                bool               `long:"sparse" description:"With --range, write each range at its offset in a sparse file the size of the object instead of back to back"`
+	VersionID             string             `long:"version-id" description:"Copy this version of the source object (also available as a ?versionId= query on the source)"`
+	Recursive             bool               `short:"r" long:"recursive" description:"Copy directories or folders recursively"`
+	Report                string             `long:"report" description:"Write a JSON report of every file or object copied to this file"`
+	Resume                bool               `long:"resume" description:"Resume an interrupted download or multipart upload, only transferring the parts that are missing"`
+	Sync                  bool               `long:"sync" description:"Only copy files or objects whose destination is missing, has a different size or is older than the source (or has a different checksum with --checksum)"`
+	Version               bool               `long:"version" description:"Print the current version"`
+	S3Url                 string             `long:"s3_url" description:"A custom s3 API url (also available as an environment variable 'S3PARCP_S3_URL', the flag takes precedence)"`
+	BandwidthLimit        string             `long:"bandwidth-limit" description:"Limit the combined rate of all uploads and downloads, for example 500MiB/s"`
+	MaxRetries            int                `long:"max-retries" description:"Max per chunk retries" default:"3"`
+	RequestPayer          bool               `long:"request-payer" description:"Agree to pay for requests to requester-pays buckets"`
+	DisableSSL            bool               `long:"disable-ssl" description:"Disable SSL"`
+	Delete                bool               `long:"delete" description:"With --sync and --recursive, delete destination files or objects that are not in the source"`
+	MaxDelete             int                `long:"max-delete" description:"Refuse to delete anything if --delete would delete more than this many files or objects, negative for no limit" default:"1000"`
+	Include               func(string)       `long:"include" description:"With --recursive, copy paths matching this glob pattern even if a later --exclude matches them (can be repeated, patterns are applied in order)"`
+	Exclude               func(string)       `long:"exclude" description:"With --recursive, skip paths matching this glob pattern unless an earlier --include matches them (can be repeated, patterns are applied in order)"`
+	Filters               []Filter           `no-flag:"true"`
+	DryRun                bool               `long:"dry-run" description:"Print the files or objects that would be copied without copying them"`
+	Fsync                 bool               `long:"fsync" description:"Flush downloaded files to disk before moving them into place"`
+	FileCachedCredentials bool               `long:"file-cached-credentials" description:"Cache AWS credentials to the file system"`
+	Verbose               bool               `short:"v" long:"verbose" description:"verbose logging"`
 	Positional            struct {
 		Source      flags.Filename `description:"Source to copy from"`
 		Destination flags.Filename `description:"Destination to copy to (Optional, defaults to source's base name)"`
 	} `positional-args:"yes"`
 }
 
+// Filter is an --include or --exclude pattern
+type Filter struct {
+	Include bool
+	Pattern string
+}
+
+// StorageClassRule is a --storage-class-over size and storage class
+type StorageClassRule struct {
+	MinSize      int64
+	StorageClass string
+}
+
+// parseKeyValue splits a key=value argument, values may contain =
+func parseKeyValue(flag string, arg string) (string, string, error) {
+	key, value, found := strings.Cut(arg, "=")
+	if !found || key == "" {
+		return "", "", fmt.Errorf("invalid %s %s, expected key=value", flag, arg)
+	}
+	return key, value, nil
+}
+
+// withoutVersionID removes a ?versionId= query from an s3 path
+func withoutVersionID(source string) string {
+	sourceURL, err := url.Parse(source)
+	if err != nil || sourceURL.Scheme != "s3" || sourceURL.Query().Get("versionId") == "" {
+		return source
+	}
+	return strings.TrimSuffix(source, "?"+sourceURL.RawQuery)
+}
+
 // ParseArgs wraps flags.ParseArgs and adds system-dependent defaults
 func ParseArgs(args []string) (Options, error) {
 	var opts Options
+
+	// --include and --exclude are collected together to keep their order
+	opts.Include = func(pattern string) {
+		opts.Filters = append(opts.Filters, Filter{Include: true, Pattern: pattern})
+	}
+	opts.Exclude = func(pattern string) {
+		opts.Filters = append(opts.Filters, Filter{Include: false, Pattern: pattern})
+	}
+
+	opts.AddMetadata = func(arg string) error {
+		key, value, err := parseKeyValue("--metadata", arg)
+		if err != nil {
+			return err
+		}
+		if opts.Metadata == nil {
+			opts.Metadata = map[string]string{}
+		}
+		opts.Metadata[key] = value
+		return nil
+	}
+	opts.AddTag = func(arg string) error {
+		key, value, err := parseKeyValue("--tag", arg)
+		if err != nil {
+			return err
+		}
+		if opts.Tags == nil {
+			opts.Tags = map[string]string{}
+		}
+		opts.Tags[key] = value
+		return nil
+	}
+
+	opts.AddStorageClassRule = func(arg string) error {
+		size, storageClass, err := parseKeyValue("--storage-class-over", arg)
+		if err != nil {
+			return err
+		}
+		minSize, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || minSize < 0 {
+			return fmt.Errorf("invalid --storage-class-over size %s, expected a number of bytes", size)
+		}
+		opts.StorageClassRules = append(opts.StorageClassRules, StorageClassRule{MinSize: minSize, StorageClass: storageClass})
+		return nil
+	}
+
 	_, err := flags.ParseArgs(&opts, args)
 	if err != nil {
 		return opts, err
@@ -43,12 +159,32 @@ func ParseArgs(args []string) (Options, error) {
 		return opts, errors.New(message)
 	}
 
-	if opts.Positional.Destination == "" {
-		opts.Positional.Destination = flags.Filename(path.Base(string(opts.Positional.Source)))
+	if opts.Delete && (!opts.Sync || !opts.Recursive) {
+		message := "--delete requires --sync and --recursive"
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", message))
+		return opts, errors.New(message)
 	}
 
-	if opts.PartSize == 0 {
-		opts.PartSize = int64(os.Getpagesize()) * 1024 * 10
+	if opts.PartSize != 0 && (opts.PartSize < minPartSize || opts.PartSize > maxPartSize) {
+		message := fmt.Sprintf("invalid --part-size %d, expected between %d (5 MiB) and %d (5 GiB) bytes", opts.PartSize, minPartSize, maxPartSize)
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", message))
+		return opts, errors.New(message)
+	}
+
+	if opts.Sparse && len(opts.Ranges) == 0 {
+		message := "--sparse requires --range"
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", message))
+		return opts, errors.New(message)
+	}
+
+	if len(opts.Ranges) > 0 && (opts.Checksum || opts.Resume) {
+		message := "--range can't be combined with --checksum or --resume"
+		os.Stderr.WriteString(fmt.Sprintf("%s\n", message))
+		return opts, errors.New(message)
+	}
+
+	if opts.Positional.Destination == "" {
+		opts.Positional.Destination = flags.Filename(path.Base(withoutVersionID(string(opts.Positional.Source))))
 	}
 
 	if opts.Concurrency == 0 {
@@ -26,3 +26,74 @@ func TestDefaults(t *testing.T) {
 		t.Errorf("expected opts.Concurrency: %d to equal runtime.NumCPU(): %d", opts.Concurrency, runtime.NumCPU())
 	}
 }
+
+func TestMetadataAndTags(t *testing.T) {
+	opts, err := ParseArgs([]string{"--metadata", "project=genomes", "--metadata", "query=a=b", "--tag", "team=data", "source"})
+
+	if err != nil {
+		t.Errorf("encountered error while parsing args %s", err)
+		t.FailNow()
+	}
+
+	if opts.Metadata["project"] != "genomes" || opts.Metadata["query"] != "a=b" {
+		t.Errorf("expected opts.Metadata: %v to contain project=genomes and query=a=b", opts.Metadata)
+	}
+
+	if len(opts.Tags) != 1 || opts.Tags["team"] != "data" {
+		t.Errorf("expected opts.Tags: %v to equal team=data", opts.Tags)
+	}
+}
+
+func TestInvalidMetadata(t *testing.T) {
+	_, err := ParseArgs([]string{"--metadata", "project", "source"})
+
+	if err == nil {
+		t.Errorf("expected error parsing --metadata without a value")
+	}
+}
+
+func TestInvalidPartSize(t *testing.T) {
+	for _, partSize := range []string{"1024", "5368709121"} {
+		_, err := ParseArgs([]string{"--part-size", partSize, "source"})
+
+		if err == nil {
+			t.Errorf("expected error parsing --part-size %s", partSize)
+		}
+	}
+
+	opts, err := ParseArgs([]string{"--part-size", "5242880", "source"})
+	if err != nil || opts.PartSize != 5242880 {
+		t.Errorf("expected --part-size 5242880 to be valid but got %d (error: %v)", opts.PartSize, err)
+	}
+}
+
+func TestStorageClassRules(t *testing.T) {
+	opts, err := ParseArgs([]string{"--storage-class-over", "1048576=GLACIER_IR", "source"})
+
+	if err != nil {
+		t.Errorf("encountered error while parsing args %s", err)
+		t.FailNow()
+	}
+
+	if len(opts.StorageClassRules) != 1 || opts.StorageClassRules[0] != (StorageClassRule{MinSize: 1048576, StorageClass: "GLACIER_IR"}) {
+		t.Errorf("expected opts.StorageClassRules: %v to equal 1048576=GLACIER_IR", opts.StorageClassRules)
+	}
+
+	_, err = ParseArgs([]string{"--storage-class-over", "large=GLACIER_IR", "source"})
+	if err == nil {
+		t.Errorf("expected error parsing --storage-class-over with an invalid size")
+	}
+}
+
+func TestVersionedSourceDestination(t *testing.T) {
+	opts, err := ParseArgs([]string{"s3://bucket/dir/object?versionId=abc"})
+
+	if err != nil {
+		t.Errorf("encountered error while parsing args %s", err)
+		t.FailNow()
+	}
+
+	if string(opts.Positional.Destination) != "object" {
+		t.Errorf("expected opts.Positional.Destination: %s to equal object", opts.Positional.Destination)
+	}
+}
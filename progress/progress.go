@@ -0,0 +1,268 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ttyInterval = 500 * time.Millisecond
+	logInterval = 30 * time.Second
+)
+
+// HTTPClient is the interface the aws sdk uses to send requests
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// Tracker counts transferred bytes and completed files across all copy jobs and
+// periodically reports aggregate throughput and ETA. All methods are safe to
+// call on a nil Tracker, which tracks nothing.
+type Tracker struct {
+	bytes      int64
+	totalBytes int64
+	files      int64
+	totalFiles int64
+
+	out      io.Writer
+	tty      bool
+	interval time.Duration
+	start    time.Time
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+}
+
+// NewTracker creates a Tracker that reports to out, redrawing a single status
+// line if out is a terminal and logging periodically otherwise
+func NewTracker(out *os.File) *Tracker {
+	tty := false
+	if stat, err := out.Stat(); err == nil {
+		tty = stat.Mode()&os.ModeCharDevice != 0
+	}
+
+	interval := logInterval
+	if tty {
+		interval = ttyInterval
+	}
+
+	return &Tracker{
+		out:      out,
+		tty:      tty,
+		interval: interval,
+	}
+}
+
+// AddJob registers a job with the tracker, size is negative if it is not known
+func (t *Tracker) AddJob(size int64) {
+	if t == nil {
+		return
+	}
+
+	atomic.AddInt64(&t.totalFiles, 1)
+	if size > 0 {
+		atomic.AddInt64(&t.totalBytes, size)
+	}
+}
+
+// AddBytes records bytes that have been transferred
+func (t *Tracker) AddBytes(n int64) {
+	if t == nil {
+		return
+	}
+
+	atomic.AddInt64(&t.bytes, n)
+}
+
+// CompleteJob records that a job has finished
+func (t *Tracker) CompleteJob() {
+	if t == nil {
+		return
+	}
+
+	atomic.AddInt64(&t.files, 1)
+}
+
+// Start begins periodically reporting progress
+func (t *Tracker) Start() {
+	if t == nil {
+		return
+	}
+
+	t.start = time.Now()
+	t.stop = make(chan struct{})
+	t.stopped.Add(1)
+	go func() {
+		defer t.stopped.Done()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.report()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops reporting progress and reports the final totals
+func (t *Tracker) Stop() {
+	if t == nil || t.stop == nil {
+		return
+	}
+
+	close(t.stop)
+	t.stopped.Wait()
+	t.report()
+	if t.tty {
+		fmt.Fprintln(t.out)
+	}
+}
+
+// report writes the current status
+func (t *Tracker) report() {
+	status := t.status(time.Since(t.start))
+	if t.tty {
+		// Pad with spaces to clear any leftovers from a longer previous line
+		fmt.Fprintf(t.out, "\r%-80s", status)
+	} else {
+		log.Println(status)
+	}
+}
+
+// status formats the progress after elapsed time has passed
+func (t *Tracker) status(elapsed time.Duration) string {
+	bytes := atomic.LoadInt64(&t.bytes)
+	totalBytes := atomic.LoadInt64(&t.totalBytes)
+	files := atomic.LoadInt64(&t.files)
+	totalFiles := atomic.LoadInt64(&t.totalFiles)
+
+	// Retried requests can transfer the same bytes twice
+	if bytes > totalBytes {
+		totalBytes = bytes
+	}
+
+	parts := []string{}
+	if totalBytes > 0 {
+		parts = append(parts, fmt.Sprintf("%s / %s (%d%%)", FormatBytes(bytes), FormatBytes(totalBytes), bytes*100/totalBytes))
+	} else {
+		parts = append(parts, FormatBytes(bytes))
+	}
+	parts = append(parts, fmt.Sprintf("%d/%d files", files, totalFiles))
+
+	seconds := elapsed.Seconds()
+	if seconds > 0 {
+		rate := float64(bytes) / seconds
+		parts = append(parts, fmt.Sprintf("%s/s", FormatBytes(int64(rate))))
+		if rate > 0 && totalBytes > bytes {
+			eta := time.Duration(float64(totalBytes-bytes) / rate * float64(time.Second))
+			parts = append(parts, fmt.Sprintf("ETA %s", eta.Round(time.Second)))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// FormatBytes formats a number of bytes with a binary unit
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// countingReader counts the bytes read from an io.Reader
+type countingReader struct {
+	io.Reader
+	tracker *Tracker
+}
+
+// Read reads from the underlying reader and counts the bytes read
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.tracker.AddBytes(int64(n))
+	return n, err
+}
+
+// Reader counts the bytes read from r
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+
+	return countingReader{Reader: r, tracker: t}
+}
+
+// countingReadCloser counts the bytes read from an io.ReadCloser
+type countingReadCloser struct {
+	countingReader
+	closer io.Closer
+}
+
+// Close closes the underlying io.ReadCloser
+func (r countingReadCloser) Close() error {
+	return r.closer.Close()
+}
+
+func (t *Tracker) readCloser(r io.ReadCloser) io.ReadCloser {
+	return countingReadCloser{
+		countingReader: countingReader{Reader: r, tracker: t},
+		closer:         r,
+	}
+}
+
+// countingHTTPClient counts the bytes sent in request bodies and/or received
+// in response bodies
+type countingHTTPClient struct {
+	client    HTTPClient
+	tracker   *Tracker
+	requests  bool
+	responses bool
+}
+
+// Do sends a request, counting the body bytes as they are transferred
+func (c countingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if c.requests && req.Body != nil && req.Body != http.NoBody {
+		req.Body = c.tracker.readCloser(req.Body)
+	}
+
+	resp, err := c.client.Do(req)
+	if err == nil && c.responses && resp.Body != nil {
+		resp.Body = c.tracker.readCloser(resp.Body)
+	}
+
+	return resp, err
+}
+
+// CountRequestBodies wraps an HTTPClient to count the bytes of request bodies it sends
+func (t *Tracker) CountRequestBodies(client HTTPClient) HTTPClient {
+	if t == nil {
+		return client
+	}
+
+	return countingHTTPClient{client: client, tracker: t, requests: true}
+}
+
+// CountResponseBodies wraps an HTTPClient to count the bytes of response bodies it receives
+func (t *Tracker) CountResponseBodies(client HTTPClient) HTTPClient {
+	if t == nil {
+		return client
+	}
+
+	return countingHTTPClient{client: client, tracker: t, responses: true}
+}
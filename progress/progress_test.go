@@ -0,0 +1,59 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                      "0 B",
+		1023:                   "1023 B",
+		1024:                   "1.0 KiB",
+		1536:                   "1.5 KiB",
+		5 * 1024 * 1024 * 1024: "5.0 GiB",
+	}
+
+	for n, expected := range cases {
+		if actual := FormatBytes(n); actual != expected {
+			t.Errorf("expected FormatBytes(%d) to equal %s but it was %s", n, expected, actual)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tracker := Tracker{}
+	tracker.AddJob(1024)
+	tracker.AddJob(1024)
+	tracker.AddBytes(1024)
+	tracker.CompleteJob()
+
+	expected := "1.0 KiB / 2.0 KiB (50%), 1/2 files, 512 B/s, ETA 2s"
+	if actual := tracker.status(2 * time.Second); actual != expected {
+		t.Errorf("expected status %s but it was %s", expected, actual)
+	}
+}
+
+func TestReaderCounts(t *testing.T) {
+	tracker := Tracker{}
+	_, err := io.Copy(ioutil.Discard, tracker.Reader(bytes.NewReader(make([]byte, 100))))
+	if err != nil {
+		t.Errorf("encountered error while reading %s", err)
+	}
+
+	if tracker.bytes != 100 {
+		t.Errorf("expected tracker to count 100 bytes but it counted %d", tracker.bytes)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.AddJob(1)
+	tracker.AddBytes(1)
+	tracker.CompleteJob()
+	tracker.Start()
+	tracker.Stop()
+}
@@ -5,11 +5,17 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/chanzuckerberg/s3parcp/bandwidth"
 	"github.com/chanzuckerberg/s3parcp/filecachedcredentials"
 	"github.com/chanzuckerberg/s3parcp/options"
 	"github.com/chanzuckerberg/s3parcp/s3utils"
@@ -33,6 +39,16 @@ func main() {
 		return
 	}
 
+	// Cancel in-flight transfers on SIGINT or SIGTERM so partial files and
+	//   multipart uploads can be cleaned up before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// Restore the default behavior so a second signal exits immediately
+		stop()
+	}()
+
 	configFuncs := make([]func(*config.LoadOptions) error, 0)
 
 	if opts.MaxRetries != 0 {
@@ -57,7 +73,7 @@ func main() {
 		configFuncs = append(configFuncs, config.WithEndpointResolverWithOptions(customDomainResolver))
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.Background(), configFuncs...)
+	cfg, err := config.LoadDefaultConfig(ctx, configFuncs...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -75,6 +91,12 @@ func main() {
 		if opts.S3Url != "" {
 			o.UsePathStyle = true
 		}
+
+		// Requester-pays buckets reject every request that doesn't accept
+		//   the charges so set the header on all of them
+		if opts.RequestPayer {
+			o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue("X-Amz-Request-Payer", string(types.RequestPayerRequester)))
+		}
 	})
 
 	sourcePath, err := s3utils.NewPath(client, string(opts.Positional.Source))
@@ -83,23 +105,159 @@ func main() {
 		os.Exit(1)
 	}
 
+	if opts.VersionID != "" {
+		sourcePath, err = s3utils.WithVersionID(sourcePath, opts.VersionID)
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("%s\n", err))
+			os.Exit(1)
+		}
+	}
+
 	destPath, err := s3utils.NewPath(client, string(opts.Positional.Destination))
 	if err != nil {
 		os.Stderr.WriteString(fmt.Sprintf("%s\n", err))
 		os.Exit(1)
 	}
 
+	var storageClasses *s3utils.StorageClasses = nil
+	if opts.StorageClass != "" || len(opts.StorageClassRules) > 0 {
+		storageClasses = &s3utils.StorageClasses{}
+		if opts.StorageClass != "" {
+			err = storageClasses.SetDefault(opts.StorageClass)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+			}
+		}
+		for _, rule := range opts.StorageClassRules {
+			err = storageClasses.AddRule(rule.MinSize, rule.StorageClass)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+			}
+		}
+	}
+
+	var encryption *s3utils.Encryption = nil
+	if opts.SSE != "" || opts.SSEKMSKeyID != "" || opts.SSECKeyFile != "" {
+		encryption, err = s3utils.NewEncryption(opts.SSE, opts.SSEKMSKeyID, opts.SSECKeyFile)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	}
+	sourcePath = s3utils.WithEncryption(sourcePath, encryption)
+	destPath = s3utils.WithEncryption(destPath, encryption)
+
+	ranges := make([]s3utils.ByteRange, len(opts.Ranges))
+	for i, r := range opts.Ranges {
+		ranges[i], err = s3utils.ParseByteRange(r)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	}
+
+	var bandwidthLimit float64 = 0
+	if opts.BandwidthLimit != "" {
+		bandwidthLimit, err = bandwidth.ParseRate(opts.BandwidthLimit)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+	}
+
 	copierOpts := s3utils.CopierOptions{
-		BufferSize:  opts.BufferSize,
-		Checksum:    opts.Checksum,
-		Concurrency: opts.Concurrency,
-		DisableSSL:  opts.DisableSSL,
-		MaxRetries:  opts.MaxRetries,
-		PartSize:    opts.PartSize,
-		Verbose:     opts.Verbose,
+		BandwidthLimit:     bandwidthLimit,
+		BufferSize:         opts.BufferSize,
+		CacheControl:       opts.CacheControl,
+		Checksum:           opts.Checksum,
+		Concurrency:        opts.Concurrency,
+		ContentDisposition: opts.ContentDisposition,
+		ContentEncoding:    opts.ContentEncoding,
+		ContentType:        opts.ContentType,
+		DisableSSL:         opts.DisableSSL,
+		Encryption:         encryption,
+		Fsync:              opts.Fsync,
+		MaxRetries:         opts.MaxRetries,
+		Metadata:           opts.Metadata,
+		PartSize:           opts.PartSize,
+		Preserve:           opts.Preserve,
+		Progress:           opts.Progress,
+		Ranges:             ranges,
+		Resume:             opts.Resume,
+		Sparse:             opts.Sparse,
+		StorageClasses:     storageClasses,
+		Tags:               opts.Tags,
+		Verbose:            opts.Verbose,
 	}
 	copier := s3utils.NewCopier(copierOpts, client)
-	jobs, err := s3utils.GetCopyJobs(sourcePath, destPath, opts.Recursive)
+
+	var filter *s3utils.Filter = nil
+	if len(opts.Filters) > 0 {
+		filter = &s3utils.Filter{}
+		for _, f := range opts.Filters {
+			if f.Include {
+				err = filter.Include(f.Pattern)
+			} else {
+				err = filter.Exclude(f.Pattern)
+			}
+			if err != nil {
+				log.Fatalf("%s\n", err)
+			}
+		}
+	}
+
+	// Jobs are streamed to the copier as the source is listed
+	copyJobs := make(chan s3utils.CopyJob, opts.Concurrency)
+	listErrors := make(chan error, 1)
+	numJobs := 0
+	go func() {
+		var err error
+		copyJobsOpts := s3utils.CopyJobsOptions{
+			DryRun:    opts.DryRun,
+			Filter:    filter,
+			Recursive: opts.Recursive,
+		}
+		numJobs, err = s3utils.GetCopyJobs(ctx, sourcePath, destPath, copyJobsOpts, copyJobs)
+		listErrors <- err
+	}()
+
+	// In sync mode only jobs whose destination doesn't match their source are copied
+	jobs := (<-chan s3utils.CopyJob)(copyJobs)
+	syncErrors := make(chan error, 1)
+	var extraneous []s3utils.Path
+	if opts.Sync {
+		changedJobs := make(chan s3utils.CopyJob, opts.Concurrency)
+		go func() {
+			var err error
+			extraneous, err = copier.SyncCopyJobs(ctx, destPath, copyJobs, changedJobs)
+			syncErrors <- err
+		}()
+		jobs = changedJobs
+	} else {
+		syncErrors <- nil
+	}
+
+	var results []s3utils.CopyResult
+	var copyErr error
+	start := time.Now()
+	if opts.DryRun {
+		s3utils.PrintCopyJobs(os.Stdout, jobs)
+	} else {
+		results, copyErr = copier.CopyAll(ctx, jobs)
+	}
+
+	if opts.Report != "" && !opts.DryRun {
+		err = s3utils.WriteReport(opts.Report, s3utils.NewReport(start, results))
+		if err != nil {
+			log.Printf("error writing report %s: %s\n", opts.Report, err)
+		}
+	}
+
+	err = <-listErrors
+	syncErr := <-syncErrors
+	if err == nil {
+		err = syncErr
+	}
+	if ctx.Err() != nil {
+		log.Fatalln("interrupted, stopped copying")
+	}
 	if err != nil {
 		if strings.HasPrefix(err.Error(), "AccessDenied") {
 			log.Println("error received from the s3 api - access denied")
@@ -112,12 +270,34 @@ func main() {
 		}
 		os.Exit(1)
 	}
-	if len(jobs) == 0 && !opts.Recursive {
+	if numJobs == 0 && !opts.Recursive {
 		log.Fatalf("no %s found at path %s\n", sourcePath.FileOrObject(), sourcePath)
 	}
 
-	err = copier.CopyAll(jobs)
-	if err != nil {
-		log.Fatalf("%s\n", err)
+	if copyErr != nil {
+		log.Fatalf("%s\n", copyErr)
+	}
+
+	// Only delete once every job has been listed and copied successfully so
+	//   a partial listing can't cause destination files to be deleted
+	if opts.Delete {
+		// Like rsync, excluded destination paths are never deleted
+		extraneous = filter.FilterPaths(destPath, extraneous)
+		if opts.MaxDelete >= 0 && len(extraneous) > opts.MaxDelete {
+			log.Fatalf("refusing to delete %d %ss from %s, more than --max-delete %d\n", len(extraneous), destPath.FileOrObject(), destPath, opts.MaxDelete)
+		}
+
+		if opts.DryRun {
+			s3utils.PrintDeletes(os.Stdout, extraneous)
+			return
+		}
+
+		err = copier.DeletePaths(ctx, extraneous)
+		if ctx.Err() != nil {
+			log.Fatalln("interrupted, stopped deleting")
+		}
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
 	}
 }
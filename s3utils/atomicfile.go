@@ -0,0 +1,70 @@
+package s3utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sync/atomic"
+)
+
+// atomicFile is a temporary file in its destination's directory that is
+// renamed to its destination once it is complete, so other processes never
+// see a partially written file
+type atomicFile struct {
+	*os.File
+	dest      string
+	committed bool
+}
+
+// tmpFileCounter keeps temporary file names unique within this process
+var tmpFileCounter uint64
+
+// createAtomicFile creates a temporary file that will be renamed to dest on commit
+func createAtomicFile(dest string) (*atomicFile, error) {
+	// Unlike os.CreateTemp, which always uses mode 0600, match os.Create
+	tmpName := fmt.Sprintf(".%s.%d-%d.s3parcp-tmp", path.Base(dest), os.Getpid(), atomic.AddUint64(&tmpFileCounter, 1))
+	file, err := os.OpenFile(path.Join(path.Dir(dest), tmpName), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	return &atomicFile{File: file, dest: dest}, nil
+}
+
+// commit moves the temporary file to its destination
+func (f *atomicFile) commit(fsync bool) error {
+	err := commitFile(f.File, f.dest, fsync)
+	if err != nil {
+		return err
+	}
+
+	f.committed = true
+	return nil
+}
+
+// discard removes the temporary file if it was not committed
+func (f *atomicFile) discard() {
+	if f.committed {
+		return
+	}
+
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// commitFile closes a completely written file and renames it to dest
+func commitFile(file *os.File, dest string, fsync bool) error {
+	if fsync {
+		err := file.Sync()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), dest)
+}
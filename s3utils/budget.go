@@ -0,0 +1,171 @@
+package s3utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// budget limits the part requests and part buffers in flight across every job
+// of a Copier. Jobs and the parts within them are started with the same
+// concurrency, so without a shared budget a recursive copy could have the
+// square of the concurrency in flight. A part always takes its buffer before
+// its request slot, and a request never waits for a buffer, so jobs can't
+// deadlock waiting for each other. All methods are safe to call on a nil
+// budget, which limits nothing.
+//
+// Part buffers are the buffers of --buffer-size and the in-memory parts of
+// downloads to stdout. Uploads from stdin are buffered by the Uploader, which
+// can't share the budget, so they upload fewer parts at once instead. Other
+// parts are read from or written to files directly and aren't buffered.
+type budget struct {
+	requests chan struct{}
+	buffers  chan struct{}
+}
+
+// newBudget creates a budget of size requests and size buffers, or nil if size isn't positive
+func newBudget(size int) *budget {
+	if size <= 0 {
+		return nil
+	}
+
+	return &budget{
+		requests: make(chan struct{}, size),
+		buffers:  make(chan struct{}, size),
+	}
+}
+
+// acquireRequest blocks until a part request may be sent or ctx is cancelled
+func (b *budget) acquireRequest(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	select {
+	case b.requests <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseRequest returns a request slot taken by acquireRequest
+func (b *budget) releaseRequest() {
+	if b == nil {
+		return
+	}
+
+	<-b.requests
+}
+
+// releasingReadCloser releases a request slot once the response body is closed
+type releasingReadCloser struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the response body and releases its request slot
+func (r releasingReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
+
+// budgetHTTPClient holds a request slot from when a request is sent until its response body is closed
+type budgetHTTPClient struct {
+	client s3.HTTPClient
+	budget *budget
+}
+
+// Do sends a request once a request slot is free
+func (c budgetHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	err := c.budget.acquireRequest(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	release := func() { once.Do(c.budget.releaseRequest) }
+
+	resp, err := c.client.Do(req)
+	if err != nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+
+	resp.Body = releasingReadCloser{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// limitRequests wraps an HTTPClient to share the budget's request slots
+func (b *budget) limitRequests(client s3.HTTPClient) s3.HTTPClient {
+	if b == nil {
+		return client
+	}
+
+	return budgetHTTPClient{client: client, budget: b}
+}
+
+// acquireBuffer blocks until a part buffer may be allocated and returns the function that releases it
+func (b *budget) acquireBuffer() func() {
+	if b == nil {
+		return func() {}
+	}
+
+	b.buffers <- struct{}{}
+	return func() { <-b.buffers }
+}
+
+// budgetReadSeekerWriteToProvider shares the budget's buffers between the parts of every upload
+type budgetReadSeekerWriteToProvider struct {
+	provider manager.ReadSeekerWriteToProvider
+	budget   *budget
+}
+
+// GetWriteTo gets a buffer from the underlying provider once the budget has one free
+func (p budgetReadSeekerWriteToProvider) GetWriteTo(seeker io.ReadSeeker) (manager.ReadSeekerWriteTo, func()) {
+	release := p.budget.acquireBuffer()
+	r, cleanup := p.provider.GetWriteTo(seeker)
+	return r, func() {
+		cleanup()
+		release()
+	}
+}
+
+// limitUploadBuffers wraps an upload buffer provider to share the budget's buffers
+func (b *budget) limitUploadBuffers(provider manager.ReadSeekerWriteToProvider) manager.ReadSeekerWriteToProvider {
+	if b == nil {
+		return provider
+	}
+
+	return budgetReadSeekerWriteToProvider{provider: provider, budget: b}
+}
+
+// budgetWriterReadFromProvider shares the budget's buffers between the parts of every download
+type budgetWriterReadFromProvider struct {
+	provider manager.WriterReadFromProvider
+	budget   *budget
+}
+
+// GetReadFrom gets a buffer from the underlying provider once the budget has one free
+func (p budgetWriterReadFromProvider) GetReadFrom(writer io.Writer) (manager.WriterReadFrom, func()) {
+	release := p.budget.acquireBuffer()
+	w, cleanup := p.provider.GetReadFrom(writer)
+	return w, func() {
+		cleanup()
+		release()
+	}
+}
+
+// limitDownloadBuffers wraps a download buffer provider to share the budget's buffers
+func (b *budget) limitDownloadBuffers(provider manager.WriterReadFromProvider) manager.WriterReadFromProvider {
+	if b == nil {
+		return provider
+	}
+
+	return budgetWriterReadFromProvider{provider: provider, budget: b}
+}
@@ -0,0 +1,80 @@
+package s3utils
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeHTTPClient records the most requests that were in flight at once
+type fakeHTTPClient struct {
+	inFlight    int64
+	maxInFlight int64
+}
+
+func (c *fakeHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	n := atomic.AddInt64(&c.inFlight, 1)
+	for {
+		max := atomic.LoadInt64(&c.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt64(&c.maxInFlight, max, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	atomic.AddInt64(&c.inFlight, -1)
+	return &http.Response{Body: ioutil.NopCloser(strings.NewReader("body"))}, nil
+}
+
+func TestBudgetLimitsRequests(t *testing.T) {
+	fake := &fakeHTTPClient{}
+	client := newBudget(2).limitRequests(fake)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Errorf("encountered error while sending request %s", err)
+				return
+			}
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if fake.maxInFlight > 2 {
+		t.Errorf("expected at most 2 requests in flight but there were %d", fake.maxInFlight)
+	}
+}
+
+func TestBudgetHoldsRequestUntilClose(t *testing.T) {
+	b := newBudget(1)
+	client := b.limitRequests(&fakeHTTPClient{})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("encountered error while sending request %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.acquireRequest(ctx); err == nil {
+		t.Errorf("expected the request slot to be held until the response body is closed")
+	}
+
+	resp.Body.Close()
+	resp.Body.Close()
+	if err := b.acquireRequest(context.Background()); err != nil {
+		t.Errorf("expected the request slot to be released once but got %s", err)
+	}
+}
@@ -0,0 +1,235 @@
+package s3utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ByteRange is an inclusive range of bytes in an object
+type ByteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// String formats a ByteRange as an http Range header value
+func (r ByteRange) String() string {
+	return fmt.Sprintf("bytes=%d-%d", r.Start, r.End)
+}
+
+// downloadCheckpoint records which byte ranges of an object have been downloaded
+type downloadCheckpoint struct {
+	ETag            string      `json:"etag"`
+	Size            int64       `json:"size"`
+	CompletedRanges []ByteRange `json:"completedRanges"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+// checkpointPath gets the path of the sidecar checkpoint file for a download destination
+func checkpointPath(dest string) string {
+	return dest + ".s3parcp-checkpoint"
+}
+
+// partialPath gets the path a resumable download is written to until it is complete
+func partialPath(dest string) string {
+	return dest + ".s3parcp-partial"
+}
+
+// loadDownloadCheckpoint reads a checkpoint from disk, returning nil if there is none
+func loadDownloadCheckpoint(path string) (*downloadCheckpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := downloadCheckpoint{path: path}
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint %s: %s", path, err)
+	}
+
+	return &checkpoint, nil
+}
+
+// save atomically writes the checkpoint to disk, the caller must hold the mutex
+func (c *downloadCheckpoint) save() error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := c.path + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, c.path)
+}
+
+// complete records a byte range as downloaded and persists the checkpoint
+func (c *downloadCheckpoint) complete(r ByteRange) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.CompletedRanges = mergeRanges(append(c.CompletedRanges, r))
+	return c.save()
+}
+
+// mergeRanges sorts ranges and merges the ones that overlap or are adjacent,
+// which keeps a checkpoint's list short since parts mostly complete in order
+func mergeRanges(ranges []ByteRange) []ByteRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		last := len(merged) - 1
+		if last >= 0 && r.Start <= merged[last].End+1 {
+			if r.End > merged[last].End {
+				merged[last].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// missingRanges splits the bytes that have not been downloaded into ranges of at most partSize
+func (c *downloadCheckpoint) missingRanges(partSize int64) []ByteRange {
+	completed := make([]ByteRange, len(c.CompletedRanges))
+	copy(completed, c.CompletedRanges)
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].Start < completed[j].Start
+	})
+
+	missing := []ByteRange{}
+	var pos int64 = 0
+	for _, r := range completed {
+		if r.Start > pos {
+			missing = append(missing, splitRange(pos, r.Start-1, partSize)...)
+		}
+		if r.End+1 > pos {
+			pos = r.End + 1
+		}
+	}
+	missing = append(missing, splitRange(pos, c.Size-1, partSize)...)
+
+	return missing
+}
+
+// splitRange splits the bytes from start to end inclusive into ranges of at most partSize
+func splitRange(start int64, end int64, partSize int64) []ByteRange {
+	ranges := []ByteRange{}
+	for ; start <= end; start += partSize {
+		partEnd := start + partSize - 1
+		if partEnd > end {
+			partEnd = end
+		}
+		ranges = append(ranges, ByteRange{Start: start, End: partEnd})
+	}
+	return ranges
+}
+
+// offsetWriterAt shifts writes to an io.WriterAt by a fixed offset
+type offsetWriterAt struct {
+	w      io.WriterAt
+	offset int64
+}
+
+// WriteAt writes p at off plus the writer's offset
+func (w offsetWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return w.w.WriteAt(p, w.offset+off)
+}
+
+// resumeDownload downloads the ranges of an object that a previous attempt did not complete
+func (c *Copier) resumeDownload(ctx context.Context, getObjectInput s3.GetObjectInput, dest string, attributes *s3.GetObjectAttributesOutput, partSize int64) (ObjectInfo, error) {
+	etag := aws.ToString(attributes.ETag)
+	checkpoint, err := loadDownloadCheckpoint(checkpointPath(dest))
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	partial := partialPath(dest)
+	_, statErr := os.Stat(partial)
+	flags := os.O_RDWR | os.O_CREATE
+	if checkpoint == nil || os.IsNotExist(statErr) {
+		checkpoint = &downloadCheckpoint{
+			ETag: etag,
+			Size: attributes.ObjectSize,
+			path: checkpointPath(dest),
+		}
+		flags |= os.O_TRUNC
+	} else if checkpoint.ETag != etag || checkpoint.Size != attributes.ObjectSize {
+		return ObjectInfo{}, fmt.Errorf("s3://%s/%s has changed since it was partially downloaded to %s, remove %s to download it again", *getObjectInput.Bucket, *getObjectInput.Key, partial, checkpoint.path)
+	}
+
+	err = checkpoint.save()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	file, err := os.OpenFile(partial, flags, 0666)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer file.Close()
+
+	missingRanges := checkpoint.missingRanges(partSize)
+	downloaded := checkpoint.Size
+	for _, r := range missingRanges {
+		downloaded -= r.End - r.Start + 1
+	}
+	c.Progress.AddBytes(downloaded)
+
+	err = c.getRanges(ctx, getObjectInput, &etag, missingRanges, func(i int, rangeInput *s3.GetObjectInput) error {
+		r := missingRanges[i]
+		_, err := c.Downloader.Download(ctx, offsetWriterAt{w: file, offset: r.Start}, rangeInput)
+		if err != nil {
+			return err
+		}
+		return checkpoint.complete(r)
+	})
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("%s (rerun with --resume to download the remaining parts)", err)
+	}
+
+	if c.Options.Checksum {
+		err = c.verifyChecksum(ctx, *getObjectInput.Bucket, *getObjectInput.Key, aws.ToString(getObjectInput.VersionId), file)
+		if err != nil {
+			// The partial file is corrupt so start from scratch next time
+			os.Remove(checkpoint.path)
+			os.Remove(partial)
+			return ObjectInfo{}, err
+		}
+	}
+
+	if c.Options.Preserve {
+		err = c.restoreObjectAttributes(ctx, *getObjectInput.Bucket, *getObjectInput.Key, aws.ToString(getObjectInput.VersionId), file.Name())
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	err = commitFile(file, dest, c.Options.Fsync)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return objectInfoFromAttributes(attributes), os.Remove(checkpoint.path)
+}
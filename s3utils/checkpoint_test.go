@@ -0,0 +1,68 @@
+package s3utils
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestMissingRangesEmptyCheckpoint(t *testing.T) {
+	checkpoint := downloadCheckpoint{Size: 25}
+	expected := []ByteRange{{0, 9}, {10, 19}, {20, 24}}
+
+	missing := checkpoint.missingRanges(10)
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected missing ranges %v but got %v", expected, missing)
+	}
+}
+
+func TestMissingRangesPartialCheckpoint(t *testing.T) {
+	checkpoint := downloadCheckpoint{
+		Size:            40,
+		CompletedRanges: []ByteRange{{20, 29}, {0, 9}},
+	}
+	expected := []ByteRange{{10, 19}, {30, 39}}
+
+	missing := checkpoint.missingRanges(10)
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected missing ranges %v but got %v", expected, missing)
+	}
+}
+
+func TestMissingRangesCompleteCheckpoint(t *testing.T) {
+	checkpoint := downloadCheckpoint{
+		Size:            20,
+		CompletedRanges: []ByteRange{{0, 9}, {10, 19}},
+	}
+
+	missing := checkpoint.missingRanges(10)
+	if len(missing) != 0 {
+		t.Errorf("expected no missing ranges but got %v", missing)
+	}
+}
+
+func TestCompleteMergesAdjacentRanges(t *testing.T) {
+	checkpoint := downloadCheckpoint{
+		Size: 50,
+		path: path.Join(t.TempDir(), "checkpoint"),
+	}
+
+	for _, r := range []ByteRange{{10, 19}, {30, 39}, {0, 9}, {20, 29}, {45, 49}} {
+		if err := checkpoint.complete(r); err != nil {
+			t.Fatalf("encountered error while completing %v: %s", r, err)
+		}
+	}
+
+	expected := []ByteRange{{0, 39}, {45, 49}}
+	if !reflect.DeepEqual(checkpoint.CompletedRanges, expected) {
+		t.Errorf("expected completed ranges %v but got %v", expected, checkpoint.CompletedRanges)
+	}
+
+	loaded, err := loadDownloadCheckpoint(checkpoint.path)
+	if err != nil {
+		t.Fatalf("encountered error while loading checkpoint: %s", err)
+	}
+	if !reflect.DeepEqual(loaded.CompletedRanges, expected) {
+		t.Errorf("expected saved ranges %v but got %v", expected, loaded.CompletedRanges)
+	}
+}
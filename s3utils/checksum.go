@@ -0,0 +1,137 @@
+package s3utils
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+var (
+	errNoChecksum       = errors.New("no crc32c checksum")
+	errChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// crc32cOfSection computes the base64 encoded crc32c checksum of a section of a file
+func crc32cOfSection(file *os.File, start int64, length int64) (string, error) {
+	h := crc32.New(crc32cTable)
+	_, err := io.Copy(h, io.NewSectionReader(file, start, length))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// listChecksumParts lists the size and crc32c checksum of every part of an object
+func (c *Copier) listChecksumParts(ctx context.Context, bucket string, key string, versionID string) (*types.Checksum, []types.ObjectPart, error) {
+	input := s3.GetObjectAttributesInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: optionalVersionID(versionID),
+		ObjectAttributes: []types.ObjectAttributes{
+			types.ObjectAttributesChecksum,
+			types.ObjectAttributesObjectParts,
+		},
+		MaxParts: 1000,
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	var checksum *types.Checksum
+	parts := []types.ObjectPart{}
+	for {
+		res, err := c.Client.GetObjectAttributes(ctx, &input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		checksum = res.Checksum
+		if res.ObjectParts == nil {
+			return checksum, parts, nil
+		}
+		parts = append(parts, res.ObjectParts.Parts...)
+		if !res.ObjectParts.IsTruncated {
+			return checksum, parts, nil
+		}
+		input.PartNumberMarker = res.ObjectParts.NextPartNumberMarker
+	}
+}
+
+// verifyChecksum compares the crc32c checksum of a local file to the
+// checksum s3 stored for the object. For multipart objects the checksum of
+// each part is compared in parallel.
+func (c *Copier) verifyChecksum(ctx context.Context, bucket string, key string, versionID string, file *os.File) error {
+	checksum, parts, err := c.listChecksumParts(ctx, bucket, key, versionID)
+	if err != nil {
+		return err
+	}
+
+	if checksum == nil || checksum.ChecksumCRC32C == nil {
+		return fmt.Errorf("s3://%s/%s has %w, it must be uploaded with --checksum to verify its checksum", bucket, key, errNoChecksum)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if len(parts) == 0 {
+		actual, err := crc32cOfSection(file, 0, stat.Size())
+		if err != nil {
+			return err
+		}
+		if actual != *checksum.ChecksumCRC32C {
+			return fmt.Errorf("%w for s3://%s/%s: expected %s but the local file has %s", errChecksumMismatch, bucket, key, *checksum.ChecksumCRC32C, actual)
+		}
+		return nil
+	}
+
+	var start int64 = 0
+	offsets := make([]int64, len(parts))
+	for i, part := range parts {
+		offsets[i] = start
+		start += part.Size
+	}
+	if start != stat.Size() {
+		return fmt.Errorf("%w for s3://%s/%s: expected %d bytes but the local file has %d", errChecksumMismatch, bucket, key, start, stat.Size())
+	}
+
+	indexes := make(chan int, len(parts))
+	for i := range parts {
+		indexes <- i
+	}
+	close(indexes)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < c.Options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				part := parts[i]
+				actual, partErr := crc32cOfSection(file, offsets[i], part.Size)
+				if partErr == nil && actual != aws.ToString(part.ChecksumCRC32C) {
+					partErr = fmt.Errorf("%w for part %d of s3://%s/%s: expected %s but the local file has %s", errChecksumMismatch, part.PartNumber, bucket, key, aws.ToString(part.ChecksumCRC32C), actual)
+				}
+				if partErr != nil {
+					mutex.Lock()
+					err = partErr
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return err
+}
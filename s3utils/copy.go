@@ -7,10 +7,16 @@ import (
 	"io"
 	"os"
 	"path"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/chanzuckerberg/s3parcp/bandwidth"
+	"github.com/chanzuckerberg/s3parcp/progress"
 )
 
 // CopyJob defines a file/object copy
@@ -27,31 +33,107 @@ func NewCopyJob(source Path, destination Path) CopyJob {
 	}
 }
 
-// GetCopyJobs gets the jobs required to copy between two paths
-func GetCopyJobs(src Path, dest Path, recursive bool) ([]CopyJob, error) {
-	destExists, err := dest.Exists()
+// Source gets the path the job copies from
+func (j CopyJob) Source() Path {
+	return j.source
+}
+
+// Destination gets the path the job copies to
+func (j CopyJob) Destination() Path {
+	return j.destination
+}
+
+// ObjectInfo describes the data copied by a CopyJob
+type ObjectInfo struct {
+	Bytes int64
+	// ETag is the object's ETag without the quotes some responses include
+	ETag      string
+	VersionID string
+	// Checksum is the base64 encoded crc32c checksum s3 stored for the object, if any
+	Checksum string
+}
+
+// unquoteETag gets an ETag without quotes, since GetObjectAttributes returns
+// ETags without them but every other response includes them
+func unquoteETag(etag *string) string {
+	return strings.Trim(aws.ToString(etag), "\"")
+}
+
+// objectInfoFromAttributes gets the ObjectInfo of an object from its attributes
+func objectInfoFromAttributes(attributes *s3.GetObjectAttributesOutput) ObjectInfo {
+	info := ObjectInfo{
+		Bytes:     attributes.ObjectSize,
+		ETag:      unquoteETag(attributes.ETag),
+		VersionID: aws.ToString(attributes.VersionId),
+	}
+	if attributes.Checksum != nil {
+		info.Checksum = aws.ToString(attributes.Checksum.ChecksumCRC32C)
+	}
+	return info
+}
+
+// CopyResult is the outcome of a CopyJob
+type CopyResult struct {
+	ObjectInfo
+	Job      CopyJob
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// CopyErrors combines the results of every failed CopyJob into one error
+type CopyErrors []CopyResult
+
+func (e CopyErrors) Error() string {
+	failures := make([]string, len(e))
+	for i, result := range e {
+		failures[i] = fmt.Sprintf("%s -> %s: %s", result.Job.source, result.Job.destination, result.Err)
+	}
+	return fmt.Sprintf("%d copy job(s) failed:\n%s", len(e), strings.Join(failures, "\n"))
+}
+
+// CopyJobsOptions are options for getting copy jobs
+type CopyJobsOptions struct {
+	// DryRun prevents GetCopyJobs from creating the destination directory
+	DryRun bool
+	// Filter selects which paths of a recursive copy are copied, nil copies all of them
+	Filter    *Filter
+	Recursive bool
+}
+
+// GetCopyJobs sends the jobs required to copy between two paths to copyJobs
+// as the source is listed. copyJobs is closed once all jobs have been sent.
+// It returns the number of jobs sent.
+func GetCopyJobs(ctx context.Context, src Path, dest Path, opts CopyJobsOptions, copyJobs chan<- CopyJob) (int, error) {
+	defer close(copyJobs)
+
+	if dest.VersionID() != "" {
+		return 0, fmt.Errorf("cannot copy to %s: versions can't be written to", dest)
+	}
+
+	destExists, err := dest.Exists(ctx)
 	if err != nil {
-		return []CopyJob{}, err
+		return 0, err
 	}
 
-	isSrcDir, err := src.IsDir()
+	isSrcDir, err := src.IsDir(ctx)
 	if err != nil {
-		return []CopyJob{}, err
+		return 0, err
 	}
 
-	isDestDir, err := dest.IsDir()
+	isDestDir, err := dest.IsDir(ctx)
 	if destExists && err != nil {
-		return []CopyJob{}, err
+		return 0, err
 	}
 
-	if isSrcDir && !recursive {
+	if isSrcDir && !opts.Recursive {
 		error := fmt.Errorf("source %s is a %s but recursive was not specified", src, src.DirOrFolder())
-		return []CopyJob{}, error
+		return 0, error
 	}
 
-	if !isSrcDir && recursive {
+	if !isSrcDir && opts.Recursive {
 		error := fmt.Errorf("source %s is not a %s but recursive was specified", src, src.DirOrFolder())
-		return []CopyJob{}, error
+		return 0, error
 	}
 
 	if !isDestDir && isSrcDir {
@@ -60,10 +142,10 @@ func GetCopyJobs(src Path, dest Path, recursive bool) ([]CopyJob, error) {
 			//   create a local directory. This brings local behavior in line
 			//   with s3, where it is possible to upload to a non-existent folder
 			//   and the folder will be created automatically.
-			if dest.IsLocal() {
+			if dest.IsLocal() && !opts.DryRun {
 				err = os.MkdirAll(dest.String(), os.ModePerm)
 				if err != nil {
-					return []CopyJob{}, err
+					return 0, err
 				}
 			}
 
@@ -71,39 +153,65 @@ func GetCopyJobs(src Path, dest Path, recursive bool) ([]CopyJob, error) {
 			//   that the destination is a directory if the source was a directory
 			isDestDir = true
 		} else {
-			return []CopyJob{}, fmt.Errorf("cannot copy %s: %s to existing %s: %s", src.DirOrFolder(), dest.FileOrObject(), src, dest)
+			return 0, fmt.Errorf("cannot copy %s: %s to existing %s: %s", src.DirOrFolder(), dest.FileOrObject(), src, dest)
 		}
 	}
 
-	srcFilepaths, err := src.ListPathsWithPrefix()
-	copyJobs := make([]CopyJob, len(srcFilepaths))
+	srcFilepaths := make(chan Path)
+	listErrors := make(chan error, 1)
+	go func() {
+		listErrors <- src.ListPathsWithPrefix(ctx, srcFilepaths)
+		close(srcFilepaths)
+	}()
 
-	for i, srcFilepath := range srcFilepaths {
+	numJobs := 0
+	for srcFilepath := range srcFilepaths {
 		destFilepath := dest
 		if !isSrcDir && isDestDir {
 			destFilepath = destFilepath.Join(src.Base())
 		}
 		if isSrcDir && isDestDir {
-			srcPrefixLength := len(src.WithoutBucket())
-			srcFilepathWithoutBucket := srcFilepath.WithoutBucket()
-			srcFilepathSuffix := srcFilepathWithoutBucket[srcPrefixLength:]
+			srcFilepathSuffix := relativePath(src, srcFilepath)
+			if !opts.Filter.Matches(srcFilepathSuffix) {
+				continue
+			}
 			destFilepath = destFilepath.Join(srcFilepathSuffix)
 		}
-		copyJobs[i] = NewCopyJob(srcFilepath, destFilepath)
+		select {
+		case copyJobs <- NewCopyJob(srcFilepath, destFilepath):
+			numJobs++
+		case <-ctx.Done():
+			return numJobs, ctx.Err()
+		}
 	}
 
-	return copyJobs, err
+	return numJobs, <-listErrors
 }
 
 // CopierOptions are options for a copier object
 type CopierOptions struct {
-	BufferSize  int
-	Checksum    bool
-	Concurrency int
-	DisableSSL  bool
-	MaxRetries  int
-	PartSize    int64
-	Verbose     bool
+	BandwidthLimit     float64
+	BufferSize         int
+	CacheControl       string
+	Checksum           bool
+	Concurrency        int
+	ContentDisposition string
+	ContentEncoding    string
+	ContentType        string
+	DisableSSL         bool
+	Encryption         *Encryption
+	Fsync              bool
+	MaxRetries         int
+	Metadata           map[string]string
+	PartSize           int64
+	Preserve           bool
+	Progress           bool
+	Ranges             []ByteRange
+	Resume             bool
+	Sparse             bool
+	StorageClasses     *StorageClasses
+	Tags               map[string]string
+	Verbose            bool
 }
 
 // Copier holds state for copying
@@ -112,25 +220,79 @@ type Copier struct {
 	Client     *s3.Client
 	Downloader *manager.Downloader
 	Uploader   *manager.Uploader
+	Progress   *progress.Tracker
+	budget     *budget
 }
 
 // NewCopier creates a new Copier
 func NewCopier(opts CopierOptions, client *s3.Client) Copier {
+	var tracker *progress.Tracker
+	if opts.Progress {
+		tracker = progress.NewTracker(os.Stderr)
+	}
+
+	// One limiter is shared by every part of every job so the limit holds
+	//   regardless of concurrency
+	var limiter *bandwidth.Limiter
+	if opts.BandwidthLimit > 0 {
+		limiter = bandwidth.NewLimiter(opts.BandwidthLimit)
+	}
+
+	// Likewise one budget is shared by every part of every job so that at
+	//   most Concurrency part requests and buffers are in flight in total
+	partBudget := newBudget(opts.Concurrency)
+
 	downloader := manager.NewDownloader(client, func(d *manager.Downloader) {
-		d.PartSize = opts.PartSize
+		if opts.PartSize > 0 {
+			d.PartSize = opts.PartSize
+		}
 		d.Concurrency = opts.Concurrency
 		d.S3 = client
 		if opts.BufferSize > 0 {
-			d.BufferProvider = manager.NewPooledBufferedWriterReadFromProvider(opts.BufferSize)
+			d.BufferProvider = partBudget.limitDownloadBuffers(manager.NewPooledBufferedWriterReadFromProvider(opts.BufferSize))
+		}
+		if tracker != nil {
+			d.ClientOptions = append(d.ClientOptions, func(o *s3.Options) {
+				o.HTTPClient = tracker.CountResponseBodies(o.HTTPClient)
+			})
+		}
+		if limiter != nil {
+			d.ClientOptions = append(d.ClientOptions, func(o *s3.Options) {
+				o.HTTPClient = limiter.LimitBodies(o.HTTPClient)
+			})
+		}
+		if partBudget != nil {
+			d.ClientOptions = append(d.ClientOptions, func(o *s3.Options) {
+				o.HTTPClient = partBudget.limitRequests(o.HTTPClient)
+			})
 		}
 	})
 
 	uploader := manager.NewUploader(client, func(d *manager.Uploader) {
-		d.PartSize = opts.PartSize
+		if opts.PartSize > 0 {
+			d.PartSize = opts.PartSize
+		}
 		d.Concurrency = opts.Concurrency
 		d.S3 = client
+		// Keep the parts of a failed upload so it can be resumed
+		d.LeavePartsOnError = opts.Resume
 		if opts.BufferSize > 0 {
-			d.BufferProvider = manager.NewBufferedReadSeekerWriteToPool(opts.BufferSize)
+			d.BufferProvider = partBudget.limitUploadBuffers(manager.NewBufferedReadSeekerWriteToPool(opts.BufferSize))
+		}
+		if tracker != nil {
+			d.ClientOptions = append(d.ClientOptions, func(o *s3.Options) {
+				o.HTTPClient = tracker.CountRequestBodies(o.HTTPClient)
+			})
+		}
+		if limiter != nil {
+			d.ClientOptions = append(d.ClientOptions, func(o *s3.Options) {
+				o.HTTPClient = limiter.LimitBodies(o.HTTPClient)
+			})
+		}
+		if partBudget != nil {
+			d.ClientOptions = append(d.ClientOptions, func(o *s3.Options) {
+				o.HTTPClient = partBudget.limitRequests(o.HTTPClient)
+			})
 		}
 	})
 
@@ -139,14 +301,85 @@ func NewCopier(opts CopierOptions, client *s3.Client) Copier {
 		Downloader: downloader,
 		Uploader:   uploader,
 		Options:    opts,
+		Progress:   tracker,
+		budget:     partBudget,
 	}
 }
 
-func (c *Copier) download(bucket string, key string, dest string) error {
+// downloadAttributes gets the attributes of an object needed to download it
+// and the part size to download it with, which matches the object's parts
+// if it was uploaded in parts
+func (c *Copier) downloadAttributes(ctx context.Context, bucket string, key string, versionID string) (*s3.GetObjectAttributesOutput, int64, error) {
+	input := s3.GetObjectAttributesInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: optionalVersionID(versionID),
+		ObjectAttributes: []types.ObjectAttributes{
+			types.ObjectAttributesObjectParts,
+			types.ObjectAttributesEtag,
+			types.ObjectAttributesObjectSize,
+			types.ObjectAttributesChecksum,
+		},
+		MaxParts: 1,
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	attributes, err := c.Client.GetObjectAttributes(ctx, &input)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	partSize := c.partSize(attributes.ObjectSize)
+	if objectParts := attributes.ObjectParts; objectParts != nil {
+		parts := objectParts.Parts
+		if len(parts) > 0 {
+			partSize = parts[0].Size
+		}
+	}
+
+	return attributes, partSize, nil
+}
+
+// singleGet checks if an object of size bytes, negative if unknown, can be
+// downloaded with a single get. Objects that fit in one part have no parts
+// to split so they skip the attributes request, unless a range or resume
+// needs the attributes.
+func (c *Copier) singleGet(size int64) bool {
+	return size >= 0 && size <= c.partSize(size) && len(c.Options.Ranges) == 0 && !c.Options.Resume
+}
+
+// getObject downloads a whole object to w with a single get, through the
+// Downloader's client options so it counts towards progress, the bandwidth
+// limit and the concurrency budget like any part
+func (c *Copier) getObject(ctx context.Context, input *s3.GetObjectInput, w io.Writer) (ObjectInfo, error) {
+	res, err := c.Downloader.S3.GetObject(ctx, input, c.Downloader.ClientOptions...)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer res.Body.Close()
+
+	n, err := io.Copy(w, contextReader{ctx: ctx, r: res.Body})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{
+		Bytes:     n,
+		ETag:      unquoteETag(res.ETag),
+		VersionID: aws.ToString(res.VersionId),
+		Checksum:  aws.ToString(res.ChecksumCRC32C),
+	}, nil
+}
+
+// download downloads an object to dest. size is the size of the object if it
+// is known from listing or -1 otherwise.
+func (c *Copier) download(ctx context.Context, bucket string, key string, versionID string, size int64, dest string) (ObjectInfo, error) {
 	getObjectInput := s3.GetObjectInput{
-		Bucket: &bucket,
-		Key:    &key,
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: optionalVersionID(versionID),
 	}
+	getObjectInput.SSECustomerAlgorithm, getObjectInput.SSECustomerKey, getObjectInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
 
 	if c.Options.Checksum {
 		getObjectInput.ChecksumMode = types.ChecksumModeEnabled
@@ -154,41 +387,68 @@ func (c *Copier) download(bucket string, key string, dest string) error {
 
 	err := os.MkdirAll(path.Dir(dest), os.ModePerm)
 	if err != nil {
-		return fmt.Errorf("while creating directory: %s encountered error: %s", path.Dir(dest), err)
+		return ObjectInfo{}, fmt.Errorf("while creating directory: %s encountered error: %s", path.Dir(dest), err)
+	}
+
+	var partSizeResp *s3.GetObjectAttributesOutput
+	var partSize int64
+	if !c.singleGet(size) {
+		partSizeResp, partSize, err = c.downloadAttributes(ctx, bucket, key, versionID)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+
+		if len(c.Options.Ranges) > 0 {
+			return c.downloadRanges(ctx, getObjectInput, dest, partSizeResp, partSize)
+		}
+
+		if c.Options.Resume {
+			return c.resumeDownload(ctx, getObjectInput, dest, partSizeResp, partSize)
+		}
 	}
 
-	file, err := os.Create(dest)
+	file, err := createAtomicFile(dest)
 	if err != nil {
-		return err
+		return ObjectInfo{}, err
 	}
-	defer file.Close()
+	defer file.discard()
 
-	partSizeResp, err := c.Client.GetObjectAttributes(context.Background(), &s3.GetObjectAttributesInput{
-		Bucket:           &bucket,
-		Key:              &key,
-		ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesObjectParts},
-		MaxParts:         1,
-	})
+	var info ObjectInfo
+	if partSizeResp == nil {
+		info, err = c.getObject(ctx, &getObjectInput, file.File)
+	} else {
+		_, err = c.Downloader.Download(ctx, file, &getObjectInput, func(d *manager.Downloader) {
+			d.PartSize = partSize
+		})
+		info = objectInfoFromAttributes(partSizeResp)
+	}
 	if err != nil {
-		return err
+		return ObjectInfo{}, err
 	}
 
-	_, err = c.Downloader.Download(context.Background(), file, &getObjectInput, func(d *manager.Downloader) {
-		if objectParts := partSizeResp.ObjectParts; objectParts != nil {
-			parts := objectParts.Parts
-			if len(parts) > 0 {
-				d.PartSize = parts[0].Size
-			}
+	if c.Options.Checksum {
+		err = c.verifyChecksum(ctx, bucket, key, versionID, file.File)
+		if err != nil {
+			return ObjectInfo{}, err
 		}
-	})
+	}
+
+	if c.Options.Preserve {
+		err = c.restoreObjectAttributes(ctx, bucket, key, versionID, file.Name())
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	err = file.commit(c.Options.Fsync)
 	if err != nil {
-		return err
+		return ObjectInfo{}, err
 	}
 
-	return nil
+	return info, nil
 }
 
-func (c *Copier) upload(src string, bucket string, key string) error {
+func (c *Copier) upload(ctx context.Context, src string, bucket string, key string) (ObjectInfo, error) {
 	uploadInput := s3.PutObjectInput{
 		Bucket: &bucket,
 		Key:    &key,
@@ -200,60 +460,179 @@ func (c *Copier) upload(src string, bucket string, key string) error {
 
 	file, err := os.Open(src)
 	if err != nil {
-		return err
+		return ObjectInfo{}, err
 	}
 	defer file.Close()
 
+	stat, err := file.Stat()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if c.Options.Preserve {
+		uploadInput.Metadata = preserveMetadata(stat)
+	}
+	c.setUploadHeaders(&uploadInput, src)
+	c.setUploadEncryption(&uploadInput)
+	uploadInput.StorageClass = c.Options.StorageClasses.ForSize(stat.Size())
+
+	if c.Options.Resume {
+		completed, err := c.resumeUpload(ctx, file, uploadInput)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+		if completed != nil {
+			return ObjectInfo{
+				Bytes:     stat.Size(),
+				ETag:      unquoteETag(completed.ETag),
+				VersionID: aws.ToString(completed.VersionId),
+				Checksum:  aws.ToString(completed.ChecksumCRC32C),
+			}, nil
+		}
+	}
+
 	uploadInput.Body = file
-	_, err = c.Uploader.Upload(context.Background(), &uploadInput)
+	uploadOutput, err := c.Uploader.Upload(ctx, &uploadInput, func(u *manager.Uploader) {
+		u.PartSize = multipartPartSize(c.partSize(stat.Size()), stat.Size())
+	})
 	if err != nil {
-		return err
+		if c.Options.Resume {
+			return ObjectInfo{}, err
+		}
+		return ObjectInfo{}, c.abortFailedUpload(ctx, err, bucket, key)
 	}
 
-	return nil
+	return ObjectInfo{
+		Bytes:     stat.Size(),
+		ETag:      unquoteETag(uploadOutput.ETag),
+		VersionID: aws.ToString(uploadOutput.VersionID),
+		Checksum:  aws.ToString(uploadOutput.ChecksumCRC32C),
+	}, nil
 }
 
-func (c *Copier) localCopy(src string, dest string) error {
-	err := os.MkdirAll(path.Dir(dest), os.ModePerm)
-	if err != nil {
-		return err
+// abortFailedUpload aborts the multipart upload of a failed upload if the
+// uploader could not, returning the upload's error
+func (c *Copier) abortFailedUpload(ctx context.Context, err error, bucket string, key string) error {
+	// The uploader aborts failed uploads with the request's context so
+	//   it can't abort them once that context has been cancelled
+	var multiUploadFailure manager.MultiUploadFailure
+	if ctx.Err() != nil && errors.As(err, &multiUploadFailure) {
+		uploadID := multiUploadFailure.UploadID()
+		_, abortErr := c.Client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &key,
+			UploadId: &uploadID,
+		})
+		if abortErr != nil {
+			return fmt.Errorf("%s (aborting multipart upload %s also failed: %s)", err, uploadID, abortErr)
+		}
 	}
+	return err
+}
 
-	sourceFileStat, err := os.Stat(src)
-	if err != nil {
-		return err
+// contextReader stops reading from r once ctx is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from the underlying reader unless the context has been cancelled
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
+	return r.r.Read(p)
+}
 
-	if !sourceFileStat.Mode().IsRegular() {
-		return fmt.Errorf("%s is not a regular file", src)
+func (c *Copier) localCopy(ctx context.Context, src string, dest string) (ObjectInfo, error) {
+	source := os.Stdin
+	var sourceFileStat os.FileInfo
+	if src != stdioName {
+		var err error
+		sourceFileStat, err = os.Stat(src)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+
+		if !sourceFileStat.Mode().IsRegular() {
+			return ObjectInfo{}, fmt.Errorf("%s is not a regular file", src)
+		}
+
+		source, err = os.Open(src)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+		defer source.Close()
+	}
+
+	if dest == stdioName {
+		n, err := io.Copy(os.Stdout, contextReader{ctx: ctx, r: c.Progress.Reader(source)})
+		return ObjectInfo{Bytes: n}, err
 	}
 
-	source, err := os.Open(src)
+	err := os.MkdirAll(path.Dir(dest), os.ModePerm)
 	if err != nil {
-		return err
+		return ObjectInfo{}, err
 	}
-	defer source.Close()
 
-	destination, err := os.Create(dest)
+	destination, err := createAtomicFile(dest)
 	if err != nil {
-		return err
+		return ObjectInfo{}, err
 	}
-	defer destination.Close()
-	_, err = io.Copy(destination, source)
-	return err
+	defer destination.discard()
+
+	n, err := io.Copy(destination, contextReader{ctx: ctx, r: c.Progress.Reader(source)})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if c.Options.Preserve && sourceFileStat != nil {
+		err = restoreAttributes(destination.Name(), preserveMetadata(sourceFileStat), sourceFileStat.ModTime())
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	return ObjectInfo{Bytes: n}, destination.commit(c.Options.Fsync)
 }
 
-// Copy executes a copy job
-func (c *Copier) Copy(copyJob CopyJob) error {
+// Copy executes a copy job, returning information about the data copied
+func (c *Copier) Copy(ctx context.Context, copyJob CopyJob) (ObjectInfo, error) {
+	if len(c.Options.Ranges) > 0 && (!copyJob.source.IsS3() || copyJob.destination.IsS3() || isStdio(copyJob.destination)) {
+		return ObjectInfo{}, fmt.Errorf("cannot copy %s to %s: ranges can only be downloaded to a file", copyJob.source, copyJob.destination)
+	}
+
 	if copyJob.source.IsS3() && copyJob.destination.IsS3() {
-		return errors.New("copying between s3 is not yet supported")
+		srcBucket, err := copyJob.source.Bucket()
+		if err != nil {
+			return ObjectInfo{}, fmt.Errorf("path: %s was determined to be an s3 path but getting its bucket encountered error: %s", copyJob.source, err)
+		}
+
+		destBucket, err := copyJob.destination.Bucket()
+		if err != nil {
+			return ObjectInfo{}, fmt.Errorf("path: %s was determined to be an s3 path but getting its bucket encountered error: %s", copyJob.destination, err)
+		}
+
+		return c.serverSideCopy(
+			ctx,
+			srcBucket,
+			copyJob.source.WithoutBucket(),
+			copyJob.source.VersionID(),
+			destBucket,
+			copyJob.destination.WithoutBucket(),
+		)
 	} else if !copyJob.source.IsS3() && copyJob.destination.IsS3() {
 		bucket, err := copyJob.destination.Bucket()
 		if err != nil {
-			return fmt.Errorf("path: %s was determined to be an s3 path but getting its bucket encountered error: %s", copyJob.destination, err)
+			return ObjectInfo{}, fmt.Errorf("path: %s was determined to be an s3 path but getting its bucket encountered error: %s", copyJob.destination, err)
+		}
+
+		if isStdio(copyJob.source) {
+			return c.uploadStream(ctx, os.Stdin, bucket, copyJob.destination.WithoutBucket())
 		}
 
 		return c.upload(
+			ctx,
 			copyJob.source.String(),
 			bucket,
 			copyJob.destination.WithoutBucket(),
@@ -261,46 +640,124 @@ func (c *Copier) Copy(copyJob CopyJob) error {
 	} else if copyJob.source.IsS3() && !copyJob.destination.IsS3() {
 		bucket, err := copyJob.source.Bucket()
 		if err != nil {
-			return fmt.Errorf("path: %s was determined to be an s3 path but getting its bucket encountered error: %s", copyJob.source, err)
+			return ObjectInfo{}, fmt.Errorf("path: %s was determined to be an s3 path but getting its bucket encountered error: %s", copyJob.source, err)
+		}
+
+		if isStdio(copyJob.destination) {
+			return c.downloadStream(ctx, bucket, copyJob.source.WithoutBucket(), copyJob.source.VersionID(), os.Stdout)
 		}
 
 		return c.download(
+			ctx,
 			bucket,
 			copyJob.source.WithoutBucket(),
+			copyJob.source.VersionID(),
+			copyJob.source.Size(),
 			copyJob.destination.String(),
 		)
 	} else {
-		return c.localCopy(copyJob.source.String(), copyJob.destination.String())
+		return c.localCopy(ctx, copyJob.source.String(), copyJob.destination.String())
 	}
 }
 
-func copyWorker(copier *Copier, downloadJobs <-chan CopyJob, errors chan<- error) {
+func copyWorker(ctx context.Context, copier *Copier, downloadJobs <-chan CopyJob, results chan<- CopyResult) {
 	for copyJob := range downloadJobs {
-		errors <- copier.Copy(copyJob)
+		// Stop starting new jobs once cancelled but keep draining the channel
+		//   so the producer isn't blocked
+		if err := ctx.Err(); err != nil {
+			results <- CopyResult{Job: copyJob, Err: err}
+			continue
+		}
+
+		start := time.Now()
+		info, err := copier.Copy(ctx, copyJob)
+		results <- CopyResult{
+			ObjectInfo: info,
+			Job:        copyJob,
+			Start:      start,
+			Duration:   time.Since(start),
+			Err:        err,
+		}
+		copier.Progress.CompleteJob()
 	}
 }
 
-// CopyAll executes a slice of copy Jobs
-func (c *Copier) CopyAll(copyJobs []CopyJob) error {
-	numJobs := len(copyJobs)
-	copyJobsChannel := make(chan CopyJob, numJobs)
-	errorChannel := make(chan error, numJobs)
+// maxQueuedCopyJobs is the most jobs queueCopyJobs buffers ahead of the workers
+const maxQueuedCopyJobs = 10000
+
+// queueCopyJobs buffers up to maxQueuedCopyJobs jobs received from copyJobs
+// until a worker is ready for them, registering each job with the progress
+// tracker as soon as it is listed so the totals are known ahead of the copies.
+// Once the queue is full listing waits for the workers to catch up, so for
+// large listings the totals grow as more jobs are listed.
+func (c *Copier) queueCopyJobs(copyJobs <-chan CopyJob, queued chan<- CopyJob) {
+	defer close(queued)
+
+	queue := []CopyJob{}
+	for copyJobs != nil || len(queue) > 0 {
+		var next chan<- CopyJob
+		var head CopyJob
+		if len(queue) > 0 {
+			next = queued
+			head = queue[0]
+		}
 
-	for w := 0; w < c.Options.Concurrency; w++ {
-		go copyWorker(c, copyJobsChannel, errorChannel)
+		receive := copyJobs
+		if len(queue) >= maxQueuedCopyJobs {
+			receive = nil
+		}
+
+		select {
+		case copyJob, ok := <-receive:
+			if !ok {
+				copyJobs = nil
+				continue
+			}
+			c.Progress.AddJob(copyJob.source.Size())
+			queue = append(queue, copyJob)
+		case next <- head:
+			queue = queue[1:]
+		}
 	}
+}
+
+// CopyAll executes copy jobs as they are received from copyJobs until it is
+// closed. It returns a result for every job and, if any jobs failed, a
+// CopyErrors listing every failure.
+func (c *Copier) CopyAll(ctx context.Context, copyJobs <-chan CopyJob) ([]CopyResult, error) {
+	resultChannel := make(chan CopyResult, c.Options.Concurrency)
+
+	c.Progress.Start()
+	defer c.Progress.Stop()
+
+	queued := make(chan CopyJob)
+	go c.queueCopyJobs(copyJobs, queued)
 
-	for _, copyJob := range copyJobs {
-		copyJobsChannel <- copyJob
+	var wg sync.WaitGroup
+	for w := 0; w < c.Options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			copyWorker(ctx, c, queued, resultChannel)
+		}()
 	}
-	close(copyJobsChannel)
 
-	var err error = nil
-	for i := 0; i < numJobs; i++ {
-		currentError := <-errorChannel
-		if currentError != nil {
-			err = currentError
+	go func() {
+		wg.Wait()
+		close(resultChannel)
+	}()
+
+	results := []CopyResult{}
+	failures := CopyErrors{}
+	for result := range resultChannel {
+		results = append(results, result)
+		if result.Err != nil {
+			failures = append(failures, result)
 		}
 	}
-	return err
+
+	if len(failures) > 0 {
+		return results, failures
+	}
+	return results, nil
 }
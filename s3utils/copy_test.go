@@ -0,0 +1,202 @@
+package s3utils
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func writeTestFile(t *testing.T, filepath string, contents string) {
+	err := os.MkdirAll(path.Dir(filepath), os.ModePerm)
+	if err == nil {
+		err = ioutil.WriteFile(filepath, []byte(contents), 0644)
+	}
+	if err != nil {
+		t.Fatalf("encountered error while writing %s: %s", filepath, err)
+	}
+}
+
+func copyAll(t *testing.T, src string, dest string, recursive bool) ([]CopyResult, error) {
+	srcPath, err := NewPath(nil, src)
+	if err != nil {
+		t.Fatalf("encountered error while creating path %s: %s", src, err)
+	}
+
+	destPath, err := NewPath(nil, dest)
+	if err != nil {
+		t.Fatalf("encountered error while creating path %s: %s", dest, err)
+	}
+
+	copier := NewCopier(CopierOptions{Concurrency: 2, PartSize: 1024}, nil)
+	copyJobs := make(chan CopyJob)
+	listErrors := make(chan error, 1)
+	go func() {
+		_, err := GetCopyJobs(context.Background(), srcPath, destPath, CopyJobsOptions{Recursive: recursive}, copyJobs)
+		listErrors <- err
+	}()
+
+	results, err := copier.CopyAll(context.Background(), copyJobs)
+	if listErr := <-listErrors; listErr != nil {
+		t.Fatalf("encountered error while getting copy jobs: %s", listErr)
+	}
+	return results, err
+}
+
+func TestCopyAllLocalRecursive(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, path.Join(dir, "src", "a"), "aaa")
+	writeTestFile(t, path.Join(dir, "src", "b", "c"), "c")
+
+	results, err := copyAll(t, path.Join(dir, "src"), path.Join(dir, "dest"), true)
+	if err != nil {
+		t.Fatalf("encountered error while copying %s", err)
+	}
+
+	if len(results) != 2 {
+		t.Errorf("expected 2 results but got %d", len(results))
+	}
+
+	var bytes int64 = 0
+	for _, result := range results {
+		bytes += result.Bytes
+	}
+	if bytes != 4 {
+		t.Errorf("expected 4 bytes to be copied but %d were", bytes)
+	}
+
+	contents, err := ioutil.ReadFile(path.Join(dir, "dest", "b", "c"))
+	if err != nil || string(contents) != "c" {
+		t.Errorf("expected dest/b/c to contain c but it contained %s (error: %v)", contents, err)
+	}
+}
+
+func TestCopyAllReportsEveryFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, path.Join(dir, "src", "a", "x"), "x")
+	writeTestFile(t, path.Join(dir, "src", "b", "y"), "y")
+	writeTestFile(t, path.Join(dir, "src", "c"), "c")
+	// Files where directories should be make the copies into them fail
+	writeTestFile(t, path.Join(dir, "dest", "a"), "")
+	writeTestFile(t, path.Join(dir, "dest", "b"), "")
+
+	results, err := copyAll(t, path.Join(dir, "src"), path.Join(dir, "dest"), true)
+
+	var copyErrors CopyErrors
+	if !errors.As(err, &copyErrors) {
+		t.Fatalf("expected CopyErrors but got %v", err)
+	}
+
+	if len(copyErrors) != 2 {
+		t.Errorf("expected 2 failures but got %d", len(copyErrors))
+	}
+
+	if len(results) != 3 {
+		t.Errorf("expected 3 results but got %d", len(results))
+	}
+}
+
+func TestSingleGet(t *testing.T) {
+	copier := NewCopier(CopierOptions{PartSize: 1024}, nil)
+	cases := []struct {
+		size     int64
+		expected bool
+	}{
+		{0, true},
+		{1024, true},
+		{1025, false},
+		{-1, false},
+	}
+	for _, c := range cases {
+		if actual := copier.singleGet(c.size); actual != c.expected {
+			t.Errorf("expected singleGet(%d) to be %t but got %t", c.size, c.expected, actual)
+		}
+	}
+
+	copier = NewCopier(CopierOptions{PartSize: 1024, Resume: true}, nil)
+	if copier.singleGet(1) {
+		t.Errorf("expected resumable downloads to get the object's attributes")
+	}
+}
+
+func TestDownloadSmallObjectSingleGet(t *testing.T) {
+	var mutex sync.Mutex
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		mutex.Unlock()
+
+		w.Header().Set("ETag", `"etag"`)
+		w.Write([]byte("small"))
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-west-2",
+		Credentials:      aws.AnonymousCredentials{},
+		EndpointResolver: s3.EndpointResolverFromURL(server.URL),
+		UsePathStyle:     true,
+	})
+	copier := NewCopier(CopierOptions{Concurrency: 2}, client)
+
+	dest := path.Join(t.TempDir(), "small")
+	info, err := copier.download(context.Background(), "bucket", "small", "", 5, dest)
+	if err != nil {
+		t.Fatalf("encountered error while downloading: %s", err)
+	}
+
+	if len(requests) != 1 || requests[0] != "GET /bucket/small?x-id=GetObject" {
+		t.Errorf("expected a single GetObject request but got %v", requests)
+	}
+
+	// The ETag is reported without quotes like the ETags from GetObjectAttributes
+	if info.Bytes != 5 || info.ETag != "etag" {
+		t.Errorf("expected 5 bytes with etag etag but got %d bytes with etag %s", info.Bytes, info.ETag)
+	}
+
+	contents, err := ioutil.ReadFile(dest)
+	if err != nil || string(contents) != "small" {
+		t.Errorf("expected %s to contain small but it contained %s (error: %v)", dest, contents, err)
+	}
+}
+
+func TestQueueCopyJobsIsBounded(t *testing.T) {
+	copier := NewCopier(CopierOptions{Concurrency: 1}, nil)
+	job := NewCopyJob(localPath{raw: "a", size: 1}, localPath{raw: "b", size: -1})
+
+	copyJobs := make(chan CopyJob)
+	queued := make(chan CopyJob)
+	go copier.queueCopyJobs(copyJobs, queued)
+
+	for i := 0; i < maxQueuedCopyJobs; i++ {
+		copyJobs <- job
+	}
+
+	select {
+	case copyJobs <- job:
+		t.Errorf("expected the queue to stop receiving jobs once %d were queued", maxQueuedCopyJobs)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	<-queued
+	copyJobs <- job
+	close(copyJobs)
+
+	n := 1
+	for range queued {
+		n++
+	}
+	if n != maxQueuedCopyJobs+1 {
+		t.Errorf("expected %d jobs to be queued but got %d", maxQueuedCopyJobs+1, n)
+	}
+}
@@ -0,0 +1,93 @@
+package s3utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// deletePath deletes a local file or s3 object
+func (c *Copier) deletePath(ctx context.Context, p Path) error {
+	if p.IsLocal() {
+		return os.Remove(p.String())
+	}
+
+	bucket, err := p.Bucket()
+	if err != nil {
+		return err
+	}
+
+	key := p.WithoutBucket()
+	_, err = c.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	return err
+}
+
+// DeleteError is the error of one failed delete
+type DeleteError struct {
+	Path Path
+	Err  error
+}
+
+// DeleteErrors combines every failed delete into one error
+type DeleteErrors []DeleteError
+
+func (e DeleteErrors) Error() string {
+	failures := make([]string, len(e))
+	for i, failure := range e {
+		failures[i] = fmt.Sprintf("deleting %s: %s", failure.Path, failure.Err)
+	}
+	return fmt.Sprintf("%d delete(s) failed:\n%s", len(e), strings.Join(failures, "\n"))
+}
+
+// DeletePaths deletes local files or s3 objects in parallel. If any deletes
+// fail it returns a DeleteErrors listing every failure.
+func (c *Copier) DeletePaths(ctx context.Context, paths []Path) error {
+	pathsToDelete := make(chan Path, len(paths))
+	for _, p := range paths {
+		pathsToDelete <- p
+	}
+	close(pathsToDelete)
+
+	failures := DeleteErrors{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < c.Options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range pathsToDelete {
+				if ctx.Err() != nil {
+					return
+				}
+
+				if c.Options.Verbose {
+					log.Printf("deleting %s\n", p)
+				}
+				err := c.deletePath(ctx, p)
+				if err != nil {
+					mutex.Lock()
+					failures = append(failures, DeleteError{Path: p, Err: err})
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		sort.Slice(failures, func(i, j int) bool {
+			return failures[i].Path.String() < failures[j].Path.String()
+		})
+		return failures
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,38 @@
+package s3utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestDeletePathsReportsEveryFailure(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, path.Join(dir, "a"), "a")
+
+	paths := []Path{}
+	for _, name := range []string{"a", "missing1", "missing2"} {
+		p, err := NewPath(nil, path.Join(dir, name))
+		if err != nil {
+			t.Fatalf("encountered error while creating path %s: %s", name, err)
+		}
+		paths = append(paths, p)
+	}
+
+	copier := NewCopier(CopierOptions{Concurrency: 2}, nil)
+	err := copier.DeletePaths(context.Background(), paths)
+
+	var deleteErrors DeleteErrors
+	if !errors.As(err, &deleteErrors) {
+		t.Fatalf("expected DeleteErrors but got %v", err)
+	}
+	if len(deleteErrors) != 2 || deleteErrors[0].Path != paths[1] || deleteErrors[1].Path != paths[2] {
+		t.Errorf("expected both missing files to fail but got %s", err)
+	}
+
+	if _, err := os.Stat(path.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Errorf("expected a to be deleted but got %v", err)
+	}
+}
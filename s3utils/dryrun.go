@@ -0,0 +1,44 @@
+package s3utils
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/chanzuckerberg/s3parcp/progress"
+)
+
+// formatSize formats the size of a path, which may not be known
+func formatSize(size int64) string {
+	if size < 0 {
+		return "unknown size"
+	}
+	return progress.FormatBytes(size)
+}
+
+// PrintCopyJobs prints every job received from copyJobs with its size followed
+// by the totals, without copying anything
+func PrintCopyJobs(w io.Writer, copyJobs <-chan CopyJob) {
+	numJobs := 0
+	var totalBytes int64 = 0
+	for copyJob := range copyJobs {
+		size := copyJob.source.Size()
+		fmt.Fprintf(w, "%s -> %s (%s)\n", copyJob.source, copyJob.destination, formatSize(size))
+
+		numJobs++
+		if size > 0 {
+			totalBytes += size
+		}
+	}
+
+	fmt.Fprintf(w, "dry run: %d copy job(s), %s in total\n", numJobs, progress.FormatBytes(totalBytes))
+}
+
+// PrintDeletes prints every path that would be deleted followed by the total,
+// without deleting anything
+func PrintDeletes(w io.Writer, paths []Path) {
+	for _, p := range paths {
+		fmt.Fprintf(w, "delete %s\n", p)
+	}
+
+	fmt.Fprintf(w, "dry run: %d deletion(s)\n", len(paths))
+}
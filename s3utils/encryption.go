@@ -0,0 +1,84 @@
+package s3utils
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// customerKeySize is the size of an SSE-C key, s3 only supports AES256
+const customerKeySize = 32
+
+// Encryption holds the server-side encryption parameters of uploads and
+// copies, and the customer provided key needed to read SSE-C objects
+type Encryption struct {
+	serverSideEncryption types.ServerSideEncryption
+	kmsKeyID             *string
+	customerKey          *string
+	customerKeyMD5       *string
+}
+
+// NewEncryption creates the encryption parameters for an --sse mode
+// (AES256 or aws:kms), an SSE-KMS key ID, which implies aws:kms, and a
+// file holding a raw 256 bit SSE-C key. Every argument is optional.
+func NewEncryption(sse string, kmsKeyID string, customerKeyFile string) (*Encryption, error) {
+	e := Encryption{serverSideEncryption: types.ServerSideEncryption(sse)}
+
+	if kmsKeyID != "" {
+		if e.serverSideEncryption == "" {
+			e.serverSideEncryption = types.ServerSideEncryptionAwsKms
+		}
+		if e.serverSideEncryption != types.ServerSideEncryptionAwsKms {
+			return nil, fmt.Errorf("--sse-kms-key-id requires --sse %s", types.ServerSideEncryptionAwsKms)
+		}
+		e.kmsKeyID = &kmsKeyID
+	}
+
+	if customerKeyFile != "" {
+		if e.serverSideEncryption != "" {
+			return nil, errors.New("--sse-c-key-file can't be combined with --sse or --sse-kms-key-id")
+		}
+
+		key, err := os.ReadFile(customerKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != customerKeySize {
+			return nil, fmt.Errorf("%s holds a %d byte key, SSE-C requires a %d byte key", customerKeyFile, len(key), customerKeySize)
+		}
+
+		keyMD5 := md5.Sum(key)
+		e.customerKey = aws.String(base64.StdEncoding.EncodeToString(key))
+		e.customerKeyMD5 = aws.String(base64.StdEncoding.EncodeToString(keyMD5[:]))
+	}
+
+	return &e, nil
+}
+
+// serverSide gets the server-side encryption and KMS key ID of new objects
+func (e *Encryption) serverSide() (types.ServerSideEncryption, *string) {
+	if e == nil {
+		return "", nil
+	}
+	return e.serverSideEncryption, e.kmsKeyID
+}
+
+// customerKeyHeaders gets the algorithm, key and key MD5 needed to read or write SSE-C objects
+func (e *Encryption) customerKeyHeaders() (*string, *string, *string) {
+	if e == nil || e.customerKey == nil {
+		return nil, nil, nil
+	}
+	return aws.String(string(types.ServerSideEncryptionAes256)), e.customerKey, e.customerKeyMD5
+}
+
+// setUploadEncryption sets the server-side encryption of an upload from the copier's options
+func (c *Copier) setUploadEncryption(uploadInput *s3.PutObjectInput) {
+	uploadInput.ServerSideEncryption, uploadInput.SSEKMSKeyId = c.Options.Encryption.serverSide()
+	uploadInput.SSECustomerAlgorithm, uploadInput.SSECustomerKey, uploadInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+}
@@ -0,0 +1,65 @@
+package s3utils
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestNewEncryptionKMS(t *testing.T) {
+	encryption, err := NewEncryption("", "alias/compliance", "")
+	if err != nil {
+		t.Fatalf("encountered error while creating encryption %s", err)
+	}
+
+	sse, kmsKeyID := encryption.serverSide()
+	if sse != types.ServerSideEncryptionAwsKms || aws.ToString(kmsKeyID) != "alias/compliance" {
+		t.Errorf("expected aws:kms with alias/compliance but got %s with %s", sse, aws.ToString(kmsKeyID))
+	}
+
+	if _, err := NewEncryption("AES256", "alias/compliance", ""); err == nil {
+		t.Errorf("expected a KMS key ID to be invalid with AES256")
+	}
+}
+
+func TestNewEncryptionCustomerKey(t *testing.T) {
+	keyFile := path.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(strings.Repeat("k", customerKeySize)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	encryption, err := NewEncryption("", "", keyFile)
+	if err != nil {
+		t.Fatalf("encountered error while creating encryption %s", err)
+	}
+
+	algorithm, key, keyMD5 := encryption.customerKeyHeaders()
+	if aws.ToString(algorithm) != "AES256" || aws.ToString(key) == "" || aws.ToString(keyMD5) == "" {
+		t.Errorf("expected AES256 customer key headers but got %s, %s, %s", aws.ToString(algorithm), aws.ToString(key), aws.ToString(keyMD5))
+	}
+
+	if _, err := NewEncryption("aws:kms", "", keyFile); err == nil {
+		t.Errorf("expected an SSE-C key to be invalid with aws:kms")
+	}
+
+	if err := os.WriteFile(keyFile, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEncryption("", "", keyFile); err == nil {
+		t.Errorf("expected a 5 byte SSE-C key to be invalid")
+	}
+}
+
+func TestEncryptionNil(t *testing.T) {
+	var encryption *Encryption
+	if sse, kmsKeyID := encryption.serverSide(); sse != "" || kmsKeyID != nil {
+		t.Errorf("expected no server-side encryption but got %s", sse)
+	}
+	if algorithm, _, _ := encryption.customerKeyHeaders(); algorithm != nil {
+		t.Errorf("expected no customer key but got %s", *algorithm)
+	}
+}
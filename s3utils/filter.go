@@ -0,0 +1,161 @@
+package s3utils
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// filterRule includes or excludes the paths that match a pattern
+type filterRule struct {
+	include bool
+	pattern string
+	regexp  *regexp.Regexp
+}
+
+// Filter is an ordered list of include and exclude patterns. Like rsync the
+// first pattern that matches a path decides whether it is included, and paths
+// that match no pattern are included.
+//
+// Patterns are matched against paths relative to the directory or folder
+// being copied. `*` matches anything but `/`, `?` matches one character but
+// `/`, `[...]` matches a character class and `**` matches anything including
+// `/`. A pattern starting with `/` is anchored to the directory or folder
+// being copied, otherwise it matches at any depth, so `*.bam` matches every
+// bam file. A pattern ending with `/` matches everything under a matching
+// directory or folder.
+type Filter struct {
+	rules []filterRule
+}
+
+// globToRegexp converts a glob pattern to an equivalent regular expression
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return nil, fmt.Errorf("invalid pattern: empty")
+	}
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("(^|.*/)")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				expr.WriteString(".*")
+				i++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid pattern %s: unterminated [", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	if dir {
+		expr.WriteString("/.*")
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// add appends a rule to the filter
+func (f *Filter) add(include bool, pattern string) error {
+	re, err := globToRegexp(pattern)
+	if err != nil {
+		return err
+	}
+
+	f.rules = append(f.rules, filterRule{include: include, pattern: pattern, regexp: re})
+	return nil
+}
+
+// Include appends a pattern whose matching paths are included
+func (f *Filter) Include(pattern string) error {
+	return f.add(true, pattern)
+}
+
+// Exclude appends a pattern whose matching paths are excluded
+func (f *Filter) Exclude(pattern string) error {
+	return f.add(false, pattern)
+}
+
+// Matches checks whether a relative path is included by the filter
+func (f *Filter) Matches(relativePath string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, rule := range f.rules {
+		if rule.regexp.MatchString(relativePath) {
+			return rule.include
+		}
+	}
+
+	return true
+}
+
+// relativePath gets the path of p relative to the directory or folder root.
+// Local roots are compared cleaned since listed local paths are clean even
+// when the root was given as something like ./dir/
+func relativePath(root Path, p Path) string {
+	if root.IsLocal() {
+		relative, err := filepath.Rel(filepath.Clean(root.WithoutBucket()), filepath.Clean(p.WithoutBucket()))
+		if err != nil {
+			return filepath.ToSlash(filepath.Clean(p.WithoutBucket()))
+		}
+		if relative == "." {
+			return ""
+		}
+		return filepath.ToSlash(relative)
+	}
+
+	// Every key is under the bucket root, otherwise only trim whole folders
+	//   so dir doesn't match the start of dir2/key
+	prefix := strings.TrimSuffix(root.WithoutBucket(), "/")
+	key := p.WithoutBucket()
+	if prefix == "" {
+		return key
+	}
+	if key == prefix {
+		return ""
+	}
+	if strings.HasPrefix(key, prefix+"/") {
+		return key[len(prefix)+1:]
+	}
+	return key
+}
+
+// FilterPaths returns the paths under root that are included by the filter
+func (f *Filter) FilterPaths(root Path, paths []Path) []Path {
+	included := []Path{}
+	for _, p := range paths {
+		if f.Matches(relativePath(root, p)) {
+			included = append(included, p)
+		}
+	}
+
+	return included
+}
@@ -0,0 +1,119 @@
+package s3utils
+
+import "testing"
+
+func TestFilterMatches(t *testing.T) {
+	type rule struct {
+		include bool
+		pattern string
+	}
+
+	cases := []struct {
+		rules    []rule
+		path     string
+		expected bool
+	}{
+		{[]rule{}, "a/b.txt", true},
+		{[]rule{{false, "*.txt"}}, "a/b.txt", false},
+		{[]rule{{false, "*.txt"}}, "a/b.txt.gz", true},
+		{[]rule{{true, "*.bam"}, {true, "*.bai"}, {false, "*"}}, "x/y.bai", true},
+		{[]rule{{true, "*.bam"}, {true, "*.bai"}, {false, "*"}}, "x/y.txt", false},
+		{[]rule{{false, "*"}, {true, "*.bam"}}, "y.bam", false},
+		{[]rule{{false, "tmp/**"}}, "tmp/a/b", false},
+		{[]rule{{false, "tmp/**"}}, "x/tmp/a", false},
+		{[]rule{{false, "tmp/**"}}, "tmpfile", true},
+		{[]rule{{false, "/tmp/"}}, "tmp/a", false},
+		{[]rule{{false, "/tmp/"}}, "x/tmp/a", true},
+		{[]rule{{false, "a/*.txt"}}, "a/b/c.txt", true},
+		{[]rule{{false, "a/**.txt"}}, "a/b/c.txt", false},
+		{[]rule{{false, "file?.[!c]"}}, "file1.b", false},
+		{[]rule{{false, "file?.[!c]"}}, "file1.c", true},
+		{[]rule{{false, "a.b"}}, "axb", true},
+	}
+
+	for _, c := range cases {
+		filter := Filter{}
+		for _, r := range c.rules {
+			var err error
+			if r.include {
+				err = filter.Include(r.pattern)
+			} else {
+				err = filter.Exclude(r.pattern)
+			}
+			if err != nil {
+				t.Fatalf("encountered error while adding pattern %s: %s", r.pattern, err)
+			}
+		}
+
+		if actual := filter.Matches(c.path); actual != c.expected {
+			t.Errorf("expected rules %v matching %s to be %v but got %v", c.rules, c.path, c.expected, actual)
+		}
+	}
+}
+
+func TestFilterInvalidPattern(t *testing.T) {
+	filter := Filter{}
+	if err := filter.Exclude("[abc"); err == nil {
+		t.Errorf("expected an unterminated character class to be invalid")
+	}
+}
+
+func TestFilterPathsUncleanRoot(t *testing.T) {
+	root, err := NewPath(nil, "./dest/")
+	if err != nil {
+		t.Fatalf("encountered error while creating path: %s", err)
+	}
+
+	paths := []Path{}
+	for _, raw := range []string{"dest/extra.txt", "dest/a", "dest/b/extra.txt"} {
+		p, err := NewPath(nil, raw)
+		if err != nil {
+			t.Fatalf("encountered error while creating path %s: %s", raw, err)
+		}
+		paths = append(paths, p)
+	}
+
+	filter := Filter{}
+	if err := filter.Exclude("/extra.txt"); err != nil {
+		t.Fatalf("encountered error while adding pattern: %s", err)
+	}
+
+	included := filter.FilterPaths(root, paths)
+	if len(included) != 2 || included[0].String() != "dest/a" || included[1].String() != "dest/b/extra.txt" {
+		t.Errorf("expected dest/a and dest/b/extra.txt to be included but got %v", included)
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	cases := []struct {
+		root     string
+		path     string
+		expected string
+	}{
+		{".", ".bashrc", ".bashrc"},
+		{"./", ".bashrc", ".bashrc"},
+		{".", ".git/config", ".git/config"},
+		{"./", "dir/.bashrc", "dir/.bashrc"},
+		{"./dir/", "dir/.bashrc", ".bashrc"},
+		{"dir", "dir", ""},
+		{"s3://bucket/", "s3://bucket/.bashrc", ".bashrc"},
+		{"s3://bucket/dir/", "s3://bucket/dir/.bashrc", ".bashrc"},
+		{"s3://bucket/dir", "s3://bucket/dir/a/b", "a/b"},
+		{"s3://bucket/dir", "s3://bucket/dir2/a", "dir2/a"},
+	}
+
+	for _, c := range cases {
+		root, err := NewPath(nil, c.root)
+		if err != nil {
+			t.Fatalf("encountered error while creating path %s: %s", c.root, err)
+		}
+		p, err := NewPath(nil, c.path)
+		if err != nil {
+			t.Fatalf("encountered error while creating path %s: %s", c.path, err)
+		}
+
+		if actual := relativePath(root, p); actual != c.expected {
+			t.Errorf("expected %s relative to %s to be %s but got %s", c.path, c.root, c.expected, actual)
+		}
+	}
+}
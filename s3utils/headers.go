@@ -0,0 +1,84 @@
+package s3utils
+
+import (
+	"mime"
+	"net/url"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// contentType gets the Content-Type of a file from its extension, it is empty if the extension is unknown
+func contentType(name string) string {
+	return mime.TypeByExtension(filepath.Ext(name))
+}
+
+// objectTagging formats tags as the url encoded Tagging of an upload
+func objectTagging(tags map[string]string) string {
+	values := url.Values{}
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
+
+// setUploadHeaders sets the metadata, tags and content headers of an upload
+// from the copier's options. Without a ContentType option the Content-Type is
+// detected from name's extension, name is empty if it has none to detect from.
+func (c *Copier) setUploadHeaders(uploadInput *s3.PutObjectInput, name string) {
+	if len(c.Options.Metadata) > 0 && uploadInput.Metadata == nil {
+		uploadInput.Metadata = make(map[string]string, len(c.Options.Metadata))
+	}
+	for key, value := range c.Options.Metadata {
+		uploadInput.Metadata[key] = value
+	}
+
+	if len(c.Options.Tags) > 0 {
+		tagging := objectTagging(c.Options.Tags)
+		uploadInput.Tagging = &tagging
+	}
+
+	uploadContentType := c.Options.ContentType
+	if uploadContentType == "" && name != "" {
+		uploadContentType = contentType(name)
+	}
+	if uploadContentType != "" {
+		uploadInput.ContentType = &uploadContentType
+	}
+
+	if c.Options.ContentEncoding != "" {
+		uploadInput.ContentEncoding = &c.Options.ContentEncoding
+	}
+	if c.Options.ContentDisposition != "" {
+		uploadInput.ContentDisposition = &c.Options.ContentDisposition
+	}
+	if c.Options.CacheControl != "" {
+		uploadInput.CacheControl = &c.Options.CacheControl
+	}
+}
+
+// replacesMetadata checks whether the copier's options change the metadata or
+// content headers of copied objects
+func (c *Copier) replacesMetadata() bool {
+	return len(c.Options.Metadata) > 0 || c.Options.ContentType != "" || c.Options.ContentEncoding != "" || c.Options.ContentDisposition != "" || c.Options.CacheControl != ""
+}
+
+// copyHeaders gets the metadata, tags and content headers of a server-side
+// copy, which are the source's headers overridden by the copier's options
+func (c *Copier) copyHeaders(head *s3.HeadObjectOutput) s3.PutObjectInput {
+	headers := s3.PutObjectInput{
+		Metadata:           make(map[string]string, len(head.Metadata)),
+		CacheControl:       head.CacheControl,
+		ContentDisposition: head.ContentDisposition,
+		ContentEncoding:    head.ContentEncoding,
+		ContentLanguage:    head.ContentLanguage,
+		ContentType:        head.ContentType,
+	}
+	for key, value := range head.Metadata {
+		headers.Metadata[key] = value
+	}
+
+	// The source already has a Content-Type so it isn't detected from the key
+	c.setUploadHeaders(&headers, "")
+	return headers
+}
@@ -0,0 +1,87 @@
+package s3utils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestSetUploadHeaders(t *testing.T) {
+	copier := NewCopier(CopierOptions{
+		Metadata: map[string]string{"project": "genomes"},
+		Tags:     map[string]string{"team": "data science", "stage": "raw"},
+	}, nil)
+
+	uploadInput := s3.PutObjectInput{Metadata: map[string]string{metadataMtime: "1500"}}
+	copier.setUploadHeaders(&uploadInput, "dir/index.html")
+
+	if uploadInput.Metadata["project"] != "genomes" || uploadInput.Metadata[metadataMtime] != "1500" {
+		t.Errorf("expected metadata to be merged but got %v", uploadInput.Metadata)
+	}
+	if tagging := aws.ToString(uploadInput.Tagging); tagging != "stage=raw&team=data+science" {
+		t.Errorf("expected tagging stage=raw&team=data+science but got %s", tagging)
+	}
+	if contentType := aws.ToString(uploadInput.ContentType); contentType != "text/html; charset=utf-8" {
+		t.Errorf("expected detected content type text/html; charset=utf-8 but got %s", contentType)
+	}
+	if uploadInput.CacheControl != nil {
+		t.Errorf("expected no cache control but got %s", *uploadInput.CacheControl)
+	}
+}
+
+func TestSetUploadHeadersContentTypeOverride(t *testing.T) {
+	copier := NewCopier(CopierOptions{ContentType: "application/x-bam", CacheControl: "no-cache"}, nil)
+
+	uploadInput := s3.PutObjectInput{}
+	copier.setUploadHeaders(&uploadInput, "reads.html")
+
+	if contentType := aws.ToString(uploadInput.ContentType); contentType != "application/x-bam" {
+		t.Errorf("expected content type application/x-bam but got %s", contentType)
+	}
+	if cacheControl := aws.ToString(uploadInput.CacheControl); cacheControl != "no-cache" {
+		t.Errorf("expected cache control no-cache but got %s", cacheControl)
+	}
+	if uploadInput.Metadata != nil {
+		t.Errorf("expected no metadata but got %v", uploadInput.Metadata)
+	}
+}
+
+func TestCopyHeaders(t *testing.T) {
+	head := s3.HeadObjectOutput{
+		Metadata:     map[string]string{"project": "genomes", "stage": "raw"},
+		ContentType:  aws.String("application/x-bam"),
+		CacheControl: aws.String("no-cache"),
+	}
+
+	copier := NewCopier(CopierOptions{}, nil)
+	if copier.replacesMetadata() {
+		t.Errorf("expected copies without header options to keep the source's metadata")
+	}
+
+	copier = NewCopier(CopierOptions{
+		Metadata:     map[string]string{"stage": "aligned"},
+		Tags:         map[string]string{"team": "data"},
+		CacheControl: "max-age=3600",
+	}, nil)
+	if !copier.replacesMetadata() {
+		t.Errorf("expected copies with header options to replace the source's metadata")
+	}
+
+	headers := copier.copyHeaders(&head)
+	if headers.Metadata["project"] != "genomes" || headers.Metadata["stage"] != "aligned" {
+		t.Errorf("expected the source's metadata to be overridden but got %v", headers.Metadata)
+	}
+	if head.Metadata["stage"] != "raw" {
+		t.Errorf("expected the source's metadata to be left unchanged but got %v", head.Metadata)
+	}
+	if contentType := aws.ToString(headers.ContentType); contentType != "application/x-bam" {
+		t.Errorf("expected the source's content type application/x-bam but got %s", contentType)
+	}
+	if cacheControl := aws.ToString(headers.CacheControl); cacheControl != "max-age=3600" {
+		t.Errorf("expected cache control max-age=3600 but got %s", cacheControl)
+	}
+	if tagging := aws.ToString(headers.Tagging); tagging != "team=data" {
+		t.Errorf("expected tagging team=data but got %s", tagging)
+	}
+}
@@ -1,21 +1,25 @@
 package s3utils
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 type localPath struct {
-	raw    string
-	client *s3.Client
+	raw     string
+	size    int64
+	modTime time.Time
+	client  *s3.Client
 }
 
 // IsDir Checks if a localPath is a directory
-func (p localPath) IsDir() (bool, error) {
+func (p localPath) IsDir(ctx context.Context) (bool, error) {
 	// Paths with a trailing slash must be directories because creating
 	//   a file with a trailing slash doesn't work
 	if p.raw[len(p.raw)-1] == '/' {
@@ -31,7 +35,7 @@ func (p localPath) IsDir() (bool, error) {
 }
 
 // Exists Checks if a localPath exists as a file or a directory
-func (p localPath) Exists() (bool, error) {
+func (p localPath) Exists(ctx context.Context) (bool, error) {
 	_, err := os.Stat(p.raw)
 	if os.IsNotExist(err) {
 		return false, nil
@@ -59,32 +63,34 @@ func (p localPath) FileOrObject() string {
 	return "file"
 }
 
-// ListPathsWithPrefix lists all paths with the localPath as a prefix
-func (p localPath) ListPathsWithPrefix() ([]Path, error) {
-	filepaths := []Path{}
-	err := filepath.Walk(
+// ListPathsWithPrefix sends all paths with the localPath as a prefix to paths
+func (p localPath) ListPathsWithPrefix(ctx context.Context, paths chan<- Path) error {
+	return filepath.Walk(
 		p.raw,
 		func(filepath string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
 			if !info.IsDir() {
-				currentPath, err := NewPath(p.client, filepath)
-				if err != nil {
-					return err
+				currentPath := localPath{
+					raw:     filepath,
+					size:    info.Size(),
+					modTime: info.ModTime(),
+					client:  p.client,
 				}
-				filepaths = append(filepaths, currentPath)
+				return sendPath(ctx, paths, currentPath)
 			}
 			return nil
 		},
 	)
-	return filepaths, err
 }
 
 // Join joins suffixes to this path
 func (p localPath) Join(suffixes ...string) Path {
 	joinArgs := append([]string{p.raw}, suffixes...)
 	p.raw = path.Join(joinArgs...)
+	p.size = -1
+	p.modTime = time.Time{}
 	return p
 }
 
@@ -93,6 +99,17 @@ func (p localPath) Base() string {
 	return path.Base(p.raw)
 }
 
+// Size returns the size of the file if it is known from listing or -1 otherwise
+func (p localPath) Size() int64 {
+	return p.size
+}
+
+// ModTime returns the modification time of the file if it is known from listing
+// or the zero time otherwise
+func (p localPath) ModTime() time.Time {
+	return p.modTime
+}
+
 // WithoutBucket returns a raw string path without the s3 bucket
 func (p localPath) WithoutBucket() string {
 	return p.raw
@@ -103,6 +120,11 @@ func (p localPath) Bucket() (string, error) {
 	return "", fmt.Errorf("requested bucket of non-s3 path: %s", p)
 }
 
+// VersionID is always empty for a localPath
+func (p localPath) VersionID() string {
+	return ""
+}
+
 func (p localPath) String() string {
 	return p.raw
 }
@@ -0,0 +1,42 @@
+package s3utils
+
+import "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+
+const (
+	// defaultPartSize is the smallest part size picked for objects of a known size
+	defaultPartSize int64 = 1024 * 1024 * 8
+	// maxPartSize is the largest part s3 allows in a multipart upload
+	maxPartSize int64 = 1024 * 1024 * 1024 * 5
+	// unknownSizePartSize is the part size of streams, which allows
+	//   streams of up to 625 GiB within the part count limit
+	unknownSizePartSize int64 = 1024 * 1024 * 64
+)
+
+// autoPartSize picks a part size for size bytes, negative if unknown. It
+// doubles the default part size until the parts fit within s3's part count
+// limit, without going over s3's maximum part size.
+func autoPartSize(size int64) int64 {
+	if size < 0 {
+		return unknownSizePartSize
+	}
+
+	partSize := defaultPartSize
+	for partSize < maxPartSize && size > partSize*int64(manager.MaxUploadParts) {
+		partSize *= 2
+	}
+	if partSize > maxPartSize {
+		partSize = maxPartSize
+	}
+	return partSize
+}
+
+// partSize gets the part size of a job transferring size bytes, negative if
+// unknown, which is the PartSize option if it was set or picked from the size
+// otherwise. Uploads and copies pass it through multipartPartSize to respect
+// s3's limits.
+func (c *Copier) partSize(size int64) int64 {
+	if c.Options.PartSize > 0 {
+		return c.Options.PartSize
+	}
+	return autoPartSize(size)
+}
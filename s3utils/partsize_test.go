@@ -0,0 +1,50 @@
+package s3utils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestAutoPartSize(t *testing.T) {
+	const gib = 1024 * 1024 * 1024
+	cases := map[int64]int64{
+		-1:             unknownSizePartSize,
+		0:              defaultPartSize,
+		1024:           defaultPartSize,
+		10 * gib:       defaultPartSize,
+		400 * gib:      64 * 1024 * 1024,
+		5 * 1024 * gib: 1024 * 1024 * 1024,
+	}
+
+	for size, expected := range cases {
+		partSize := autoPartSize(size)
+		if partSize != expected {
+			t.Errorf("expected part size %d for size %d but got %d", expected, size, partSize)
+		}
+		if partSize < manager.MinUploadPartSize || partSize > maxPartSize {
+			t.Errorf("expected part size %d for size %d to be within s3's part size limits", partSize, size)
+		}
+		if size > 0 && (size+partSize-1)/partSize > int64(manager.MaxUploadParts) {
+			t.Errorf("expected part size %d for size %d to be within s3's part count limit", partSize, size)
+		}
+	}
+}
+
+func TestPartSizeOverride(t *testing.T) {
+	copier := NewCopier(CopierOptions{PartSize: 1024 * 1024 * 16}, nil)
+	if partSize := copier.partSize(1024); partSize != 1024*1024*16 {
+		t.Errorf("expected the PartSize option to override the part size but got %d", partSize)
+	}
+
+	copier = NewCopier(CopierOptions{}, nil)
+	if partSize := copier.partSize(1024); partSize != defaultPartSize {
+		t.Errorf("expected the default part size but got %d", partSize)
+	}
+}
+
+func TestMultipartPartSizeMax(t *testing.T) {
+	if partSize := multipartPartSize(maxPartSize+1, maxPartSize*2); partSize != maxPartSize {
+		t.Errorf("expected part size to be clamped to %d but got %d", maxPartSize, partSize)
+	}
+}
@@ -1,26 +1,32 @@
 package s3utils
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
 // Path is an interface of functions to apply transparently to s3 or local paths
 type Path interface {
-	IsDir() (bool, error)
-	Exists() (bool, error)
+	IsDir(context.Context) (bool, error)
+	Exists(context.Context) (bool, error)
 	IsS3() bool
 	IsLocal() bool
 	DirOrFolder() string
 	FileOrObject() string
-	ListPathsWithPrefix() ([]Path, error)
+	ListPathsWithPrefix(context.Context, chan<- Path) error
 	Join(...string) Path
 	Base() string
+	Size() int64
+	ModTime() time.Time
 	WithoutBucket() string
 	Bucket() (string, error)
+	VersionID() string
 	String() string
 }
 
@@ -37,6 +43,20 @@ func s3PathToBucketAndKey(s3path string) (string, string, error) {
 	return url.Host, key, nil
 }
 
+// splitVersionID splits the ?versionId= query off of an s3 path
+func splitVersionID(s3path string) (string, string) {
+	url, err := url.Parse(s3path)
+	if err != nil {
+		return s3path, ""
+	}
+
+	versionID := url.Query().Get("versionId")
+	if versionID == "" {
+		return s3path, ""
+	}
+	return strings.TrimSuffix(s3path, "?"+url.RawQuery), versionID
+}
+
 // bucketAndKeyToS3Path converts a bucket and key to an s3 path
 func bucketAndKeyToS3Path(bucket string, key string) string {
 	return fmt.Sprintf("s3://%s", path.Join(bucket, key))
@@ -60,22 +80,63 @@ func addTrailingSlash(path string) string {
 	return path
 }
 
+// sendPath sends a path to paths unless ctx is cancelled first
+func sendPath(ctx context.Context, paths chan<- Path, p Path) error {
+	select {
+	case paths <- p:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // NewPath creates a Path from a raw string
 func NewPath(client *s3.Client, raw string) (Path, error) {
+	if raw == stdioName {
+		return stdioPath{}, nil
+	}
 	if isS3Path(raw) {
+		raw, versionID := splitVersionID(raw)
 		bucket, key, err := s3PathToBucketAndKey(raw)
 		if err != nil {
 			return nil, fmt.Errorf("parsing s3 path %s: %v", raw, err)
 		}
 		return s3Path{
-			bucket: bucket,
-			prefix: key,
-			raw:    raw,
-			client: client,
+			bucket:    bucket,
+			prefix:    key,
+			raw:       raw,
+			versionID: versionID,
+			size:      -1,
+			client:    client,
 		}, nil
 	}
 	return localPath{
 		raw:    raw,
+		size:   -1,
 		client: client,
 	}, nil
 }
+
+// WithVersionID gets a Path to a specific version of an s3 object
+func WithVersionID(p Path, versionID string) (Path, error) {
+	s3p, ok := p.(s3Path)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an s3 path so it has no versions", p)
+	}
+
+	s3p.versionID = versionID
+	return s3p, nil
+}
+
+// WithEncryption gets a Path that heads s3 objects with the SSE-C key of
+// encryption, if any, since objects encrypted with SSE-C can't be headed
+// without their key. Other paths are returned unchanged.
+func WithEncryption(p Path, encryption *Encryption) Path {
+	s3p, ok := p.(s3Path)
+	if !ok {
+		return p
+	}
+
+	s3p.encryption = encryption
+	return s3p
+}
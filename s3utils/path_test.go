@@ -0,0 +1,104 @@
+package s3utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestNewPathVersionID(t *testing.T) {
+	p, err := NewPath(nil, "s3://bucket/dir/object?versionId=abc+123")
+	if err != nil {
+		t.Fatalf("encountered error while creating path %s", err)
+	}
+
+	if p.WithoutBucket() != "dir/object" {
+		t.Errorf("expected key dir/object but got %s", p.WithoutBucket())
+	}
+	if p.VersionID() != "abc 123" {
+		t.Errorf("expected version ID abc 123 but got %s", p.VersionID())
+	}
+	if p.Base() != "object" {
+		t.Errorf("expected base name object but got %s", p.Base())
+	}
+	if p.String() != "s3://bucket/dir/object?versionId=abc+123" {
+		t.Errorf("expected the version ID in %s", p.String())
+	}
+	if joined := p.Join("child"); joined.VersionID() != "" {
+		t.Errorf("expected joined path %s to have no version ID", joined)
+	}
+}
+
+func TestWithVersionID(t *testing.T) {
+	p, _ := NewPath(nil, "s3://bucket/object")
+	versioned, err := WithVersionID(p, "abc")
+	if err != nil || versioned.VersionID() != "abc" {
+		t.Errorf("expected version ID abc but got %v (%v)", versioned, err)
+	}
+
+	local, _ := NewPath(nil, "object")
+	if _, err := WithVersionID(local, "abc"); err == nil {
+		t.Errorf("expected local paths to have no versions")
+	}
+}
+
+func TestCopySourceVersionID(t *testing.T) {
+	if source := copySource("bucket", "dir/my object", "abc+1"); source != "bucket/dir/my%20object?versionId=abc%2B1" {
+		t.Errorf("expected versioned copy source but got %s", source)
+	}
+}
+
+func TestExistsCustomerKey(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "AES256" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-west-2",
+		Credentials:      aws.AnonymousCredentials{},
+		EndpointResolver: s3.EndpointResolverFromURL(server.URL),
+		UsePathStyle:     true,
+		RetryMaxAttempts: 1,
+	})
+
+	p, err := NewPath(client, "s3://bucket/object")
+	if err != nil {
+		t.Fatalf("encountered error while creating path %s", err)
+	}
+
+	if _, err := p.Exists(context.Background()); err == nil {
+		t.Errorf("expected heading an SSE-C object without its key to fail")
+	}
+
+	keyFile := path.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(strings.Repeat("k", customerKeySize)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encryption, err := NewEncryption("", "", keyFile)
+	if err != nil {
+		t.Fatalf("encountered error while creating encryption %s", err)
+	}
+	p = WithEncryption(p, encryption)
+
+	if exists, err := p.Exists(context.Background()); !exists || err != nil {
+		t.Errorf("expected the object to exist but got %t (error: %v)", exists, err)
+	}
+
+	status = http.StatusNotFound
+	if exists, err := p.Exists(context.Background()); exists || err != nil {
+		t.Errorf("expected the object not to exist but got %t (error: %v)", exists, err)
+	}
+}
@@ -0,0 +1,103 @@
+package s3utils
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Metadata keys --preserve stores file attributes in, these match s3fs
+const (
+	metadataMode  = "mode"
+	metadataUID   = "uid"
+	metadataGID   = "gid"
+	metadataMtime = "mtime"
+)
+
+// modeRegular is the file type bits of a regular file in a POSIX st_mode
+const modeRegular = 0100000
+
+// preserveMetadata gets the metadata that preserves a file's mode, ownership and mtime
+func preserveMetadata(info os.FileInfo) map[string]string {
+	metadata := map[string]string{
+		metadataMode:  strconv.FormatUint(uint64(modeRegular|info.Mode().Perm()), 10),
+		metadataMtime: strconv.FormatInt(info.ModTime().Unix(), 10),
+	}
+
+	if uid, gid, ok := fileOwner(info); ok {
+		metadata[metadataUID] = strconv.Itoa(uid)
+		metadata[metadataGID] = strconv.Itoa(gid)
+	}
+
+	return metadata
+}
+
+// metadataModTime gets the mtime preserved in metadata, falling back to lastModified
+func metadataModTime(metadata map[string]string, lastModified time.Time) time.Time {
+	// s3fs stores whole seconds but other tools store fractions
+	mtime, err := strconv.ParseFloat(metadata[metadataMtime], 64)
+	if err != nil {
+		return lastModified
+	}
+
+	seconds := int64(mtime)
+	return time.Unix(seconds, int64((mtime-float64(seconds))*float64(time.Second)))
+}
+
+// restoreAttributes applies the mode, ownership and mtime preserved in
+// metadata to a file. Attributes missing from the metadata are left as they
+// are except for the mtime, which falls back to lastModified.
+func restoreAttributes(name string, metadata map[string]string, lastModified time.Time) error {
+	uid, uidErr := strconv.Atoi(metadata[metadataUID])
+	gid, gidErr := strconv.Atoi(metadata[metadataGID])
+	if uidErr == nil && gidErr == nil {
+		// Only root can give files away, like cp -p quietly keep the current owner otherwise
+		err := chown(name, uid, gid)
+		if err != nil && !os.IsPermission(err) {
+			return err
+		}
+	}
+
+	// Change the mode after the owner since chown can clear setuid and setgid bits
+	mode, err := strconv.ParseUint(metadata[metadataMode], 10, 32)
+	if err == nil {
+		err = os.Chmod(name, os.FileMode(mode).Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	mtime := metadataModTime(metadata, lastModified)
+	return os.Chtimes(name, mtime, mtime)
+}
+
+// objectAttributes gets the metadata and last modified time of an object
+func (c *Copier) objectAttributes(ctx context.Context, bucket string, key string, versionID string) (map[string]string, time.Time, error) {
+	input := s3.HeadObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: optionalVersionID(versionID),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	res, err := c.Client.HeadObject(ctx, &input)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return res.Metadata, aws.ToTime(res.LastModified), nil
+}
+
+// restoreObjectAttributes applies the attributes preserved in an object's metadata to a file
+func (c *Copier) restoreObjectAttributes(ctx context.Context, bucket string, key string, versionID string, name string) error {
+	metadata, lastModified, err := c.objectAttributes(ctx, bucket, key, versionID)
+	if err != nil {
+		return err
+	}
+
+	return restoreAttributes(name, metadata, lastModified)
+}
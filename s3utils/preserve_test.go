@@ -0,0 +1,61 @@
+package s3utils
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestMetadataModTime(t *testing.T) {
+	lastModified := time.Unix(1000, 0)
+
+	if mtime := metadataModTime(map[string]string{}, lastModified); !mtime.Equal(lastModified) {
+		t.Errorf("expected missing mtime to fall back to %s but got %s", lastModified, mtime)
+	}
+
+	if mtime := metadataModTime(map[string]string{metadataMtime: "1500"}, lastModified); mtime.Unix() != 1500 {
+		t.Errorf("expected mtime 1500 but got %d", mtime.Unix())
+	}
+
+	if mtime := metadataModTime(map[string]string{metadataMtime: "1500.5"}, lastModified); mtime.UnixNano() != 1500500000000 {
+		t.Errorf("expected fractional mtime 1500.5 but got %s", mtime)
+	}
+}
+
+func TestRestoreAttributes(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "src")
+	dest := path.Join(dir, "dest")
+	writeTestFile(t, src, "src")
+	writeTestFile(t, dest, "dest")
+
+	mtime := time.Unix(1500, 0)
+	if err := os.Chmod(src, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	srcStat, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = restoreAttributes(dest, preserveMetadata(srcStat), time.Now())
+	if err != nil {
+		t.Fatalf("encountered error while restoring attributes %s", err)
+	}
+
+	destStat, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if destStat.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 but got %o", destStat.Mode().Perm())
+	}
+	if !destStat.ModTime().Equal(mtime) {
+		t.Errorf("expected mtime %s but got %s", mtime, destStat.ModTime())
+	}
+}
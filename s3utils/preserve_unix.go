@@ -0,0 +1,23 @@
+//go:build !windows
+
+package s3utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwner gets the uid and gid of a file
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// chown changes the uid and gid of a file
+func chown(name string, uid int, gid int) error {
+	return os.Chown(name, uid, gid)
+}
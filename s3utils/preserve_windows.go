@@ -0,0 +1,13 @@
+package s3utils
+
+import "os"
+
+// fileOwner never finds an owner since windows files have no uid or gid
+func fileOwner(info os.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// chown does nothing since windows files have no uid or gid
+func chown(name string, uid int, gid int) error {
+	return nil
+}
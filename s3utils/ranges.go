@@ -0,0 +1,200 @@
+package s3utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ParseByteRange parses a start-end range of bytes. The end is inclusive and
+// can be left out to read to the end of the object, in which case it is -1.
+func ParseByteRange(s string) (ByteRange, error) {
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		return ByteRange{}, fmt.Errorf("invalid range %s, expected start-end", s)
+	}
+
+	r := ByteRange{End: -1}
+	var err error
+	r.Start, err = strconv.ParseInt(start, 10, 64)
+	if err != nil || r.Start < 0 {
+		return ByteRange{}, fmt.Errorf("invalid range %s, expected start-end", s)
+	}
+
+	if end != "" {
+		r.End, err = strconv.ParseInt(end, 10, 64)
+		if err != nil || r.End < r.Start {
+			return ByteRange{}, fmt.Errorf("invalid range %s, expected start-end with end at least start", s)
+		}
+	}
+
+	return r, nil
+}
+
+// resolveRanges limits ranges to the bytes of an object of size bytes
+func resolveRanges(ranges []ByteRange, size int64) ([]ByteRange, error) {
+	resolved := make([]ByteRange, len(ranges))
+	for i, r := range ranges {
+		if r.Start >= size {
+			return nil, fmt.Errorf("range %d-%d starts past the end of the %d byte object", r.Start, r.End, size)
+		}
+		if r.End < 0 || r.End >= size {
+			r.End = size - 1
+		}
+		resolved[i] = r
+	}
+	return resolved, nil
+}
+
+// rangeError is the error of one range of a ranged download
+type rangeError struct {
+	ByteRange
+	index int
+	err   error
+}
+
+// rangeErrors combines the errors of every failed range of a ranged download into one error
+type rangeErrors []rangeError
+
+func (e rangeErrors) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("range %s: %s", e[0].ByteRange, e[0].err)
+	}
+
+	failures := make([]string, len(e))
+	for i, failure := range e {
+		failures[i] = fmt.Sprintf("range %s: %s", failure.ByteRange, failure.err)
+	}
+	return fmt.Sprintf("%d ranges failed:\n%s", len(e), strings.Join(failures, "\n"))
+}
+
+// getRanges gets ranges of an object in parallel, calling get with the index
+// of each range and a copy of getObjectInput for it. The gets must match etag
+// so a download fails rather than mix bytes from two versions of the object.
+// If any ranges fail it returns a rangeErrors listing every failure.
+func (c *Copier) getRanges(ctx context.Context, getObjectInput s3.GetObjectInput, etag *string, ranges []ByteRange, get func(i int, rangeInput *s3.GetObjectInput) error) error {
+	getObjectInput.IfMatch = etag
+
+	indexes := make(chan int, len(ranges))
+	for i := range ranges {
+		indexes <- i
+	}
+	close(indexes)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	failures := rangeErrors{}
+	for w := 0; w < c.Options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				rangeInput := getObjectInput
+				rangeString := ranges[i].String()
+				rangeInput.Range = &rangeString
+
+				err := get(i, &rangeInput)
+				if err != nil {
+					mutex.Lock()
+					failures = append(failures, rangeError{ByteRange: ranges[i], index: i, err: err})
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		sort.Slice(failures, func(i, j int) bool {
+			return failures[i].index < failures[j].index
+		})
+		return failures
+	}
+	return nil
+}
+
+// rangePart is a part of a requested range and the offset it is written at
+type rangePart struct {
+	ByteRange
+	offset int64
+}
+
+// splitRangeParts splits ranges into parts of at most partSize. With sparse
+// each part is written at its offset in the object, otherwise the ranges are
+// written back to back. It returns the parts and the number of bytes written.
+func splitRangeParts(ranges []ByteRange, partSize int64, sparse bool) ([]rangePart, int64) {
+	parts := []rangePart{}
+	var written int64 = 0
+	for _, r := range ranges {
+		for _, part := range splitRange(r.Start, r.End, partSize) {
+			offset := part.Start
+			if !sparse {
+				offset = written + part.Start - r.Start
+			}
+			parts = append(parts, rangePart{ByteRange: part, offset: offset})
+		}
+		written += r.End - r.Start + 1
+	}
+	return parts, written
+}
+
+// downloadRanges downloads the byte ranges of the Ranges option of an object,
+// splitting them into parts that are downloaded in parallel
+func (c *Copier) downloadRanges(ctx context.Context, getObjectInput s3.GetObjectInput, dest string, attributes *s3.GetObjectAttributesOutput, partSize int64) (ObjectInfo, error) {
+	ranges, err := resolveRanges(c.Options.Ranges, attributes.ObjectSize)
+	if err != nil {
+		return ObjectInfo{}, fmt.Errorf("s3://%s/%s: %s", *getObjectInput.Bucket, *getObjectInput.Key, err)
+	}
+
+	file, err := createAtomicFile(dest)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer file.discard()
+
+	// A sparse file is the size of the object with holes where no range was downloaded
+	if c.Options.Sparse {
+		err = file.Truncate(attributes.ObjectSize)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	parts, written := splitRangeParts(ranges, partSize, c.Options.Sparse)
+	partRanges := make([]ByteRange, len(parts))
+	for i, part := range parts {
+		partRanges[i] = part.ByteRange
+	}
+
+	err = c.getRanges(ctx, getObjectInput, attributes.ETag, partRanges, func(i int, rangeInput *s3.GetObjectInput) error {
+		_, err := c.Downloader.Download(ctx, offsetWriterAt{w: file, offset: parts[i].offset}, rangeInput)
+		return err
+	})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	if c.Options.Preserve {
+		err = c.restoreObjectAttributes(ctx, *getObjectInput.Bucket, *getObjectInput.Key, aws.ToString(getObjectInput.VersionId), file.Name())
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+
+	err = file.commit(c.Options.Fsync)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	// The object's checksum doesn't describe the bytes that were downloaded
+	info := objectInfoFromAttributes(attributes)
+	info.Bytes = written
+	info.Checksum = ""
+	return info, nil
+}
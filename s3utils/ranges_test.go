@@ -0,0 +1,97 @@
+package s3utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestParseByteRange(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected ByteRange
+	}{
+		{"0-99", ByteRange{Start: 0, End: 99}},
+		{"100-", ByteRange{Start: 100, End: -1}},
+		{"5-5", ByteRange{Start: 5, End: 5}},
+	}
+
+	for _, c := range cases {
+		r, err := ParseByteRange(c.s)
+		if err != nil {
+			t.Errorf("encountered error while parsing range %s: %s", c.s, err)
+		} else if r != c.expected {
+			t.Errorf("expected range %s to parse as %v but got %v", c.s, c.expected, r)
+		}
+	}
+
+	for _, invalid := range []string{"", "10", "-10", "10-5", "a-b"} {
+		if _, err := ParseByteRange(invalid); err == nil {
+			t.Errorf("expected range %s to be invalid", invalid)
+		}
+	}
+}
+
+func TestResolveRanges(t *testing.T) {
+	resolved, err := resolveRanges([]ByteRange{{Start: 0, End: 9}, {Start: 90, End: -1}, {Start: 95, End: 200}}, 100)
+	if err != nil {
+		t.Fatalf("encountered error while resolving ranges %s", err)
+	}
+
+	expected := []ByteRange{{Start: 0, End: 9}, {Start: 90, End: 99}, {Start: 95, End: 99}}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("expected ranges %v but got %v", expected, resolved)
+	}
+
+	if _, err := resolveRanges([]ByteRange{{Start: 100, End: -1}}, 100); err == nil {
+		t.Errorf("expected a range starting at the end of the object to be invalid")
+	}
+}
+
+func TestSplitRangeParts(t *testing.T) {
+	ranges := []ByteRange{{Start: 0, End: 9}, {Start: 50, End: 54}}
+
+	parts, written := splitRangeParts(ranges, 6, false)
+	expected := []rangePart{
+		{ByteRange{Start: 0, End: 5}, 0},
+		{ByteRange{Start: 6, End: 9}, 6},
+		{ByteRange{Start: 50, End: 54}, 10},
+	}
+	if written != 15 || !reflect.DeepEqual(parts, expected) {
+		t.Errorf("expected back to back parts %v writing 15 bytes but got %v writing %d", expected, parts, written)
+	}
+
+	parts, _ = splitRangeParts(ranges, 6, true)
+	if parts[2].offset != 50 {
+		t.Errorf("expected sparse part to be written at offset 50 but got %d", parts[2].offset)
+	}
+}
+
+func TestGetRangesReturnsEveryFailure(t *testing.T) {
+	copier := NewCopier(CopierOptions{Concurrency: 2}, nil)
+	ranges := []ByteRange{{Start: 0, End: 9}, {Start: 10, End: 19}, {Start: 20, End: 29}}
+	etag := "etag"
+
+	err := copier.getRanges(context.Background(), s3.GetObjectInput{}, &etag, ranges, func(i int, rangeInput *s3.GetObjectInput) error {
+		if aws.ToString(rangeInput.IfMatch) != etag || aws.ToString(rangeInput.Range) != ranges[i].String() {
+			return fmt.Errorf("expected range %s matching %s but got %s matching %s", ranges[i], etag, aws.ToString(rangeInput.Range), aws.ToString(rangeInput.IfMatch))
+		}
+		if i == 1 {
+			return nil
+		}
+		return fmt.Errorf("failed %d", i)
+	})
+
+	var failures rangeErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("expected rangeErrors but got %v", err)
+	}
+	if len(failures) != 2 || failures[0].err.Error() != "failed 0" || failures[1].err.Error() != "failed 2" {
+		t.Errorf("expected ranges 0 and 2 to fail but got %s", err)
+	}
+}
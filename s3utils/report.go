@@ -0,0 +1,105 @@
+package s3utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+)
+
+// Report is a machine-readable summary of a run of CopyAll
+type Report struct {
+	Start           time.Time      `json:"start"`
+	End             time.Time      `json:"end"`
+	DurationSeconds float64        `json:"durationSeconds"`
+	Totals          ReportTotals   `json:"totals"`
+	Jobs            []ReportResult `json:"jobs"`
+}
+
+// ReportTotals aggregates the results of every job in a Report
+type ReportTotals struct {
+	Jobs      int   `json:"jobs"`
+	Succeeded int   `json:"succeeded"`
+	Failed    int   `json:"failed"`
+	Skipped   int   `json:"skipped"`
+	Bytes     int64 `json:"bytes"`
+}
+
+// ReportResult describes the result of one CopyJob in a Report
+type ReportResult struct {
+	Source          string     `json:"source"`
+	Destination     string     `json:"destination"`
+	Size            int64      `json:"size"`
+	ETag            string     `json:"etag,omitempty"`
+	VersionID       string     `json:"versionId,omitempty"`
+	ChecksumCRC32C  string     `json:"checksumCRC32C,omitempty"`
+	Start           *time.Time `json:"start,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds"`
+	Status          string     `json:"status"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Statuses of a ReportResult
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// NewReport summarizes the results of a run of CopyAll that started at start
+func NewReport(start time.Time, results []CopyResult) Report {
+	end := time.Now()
+	report := Report{
+		Start:           start,
+		End:             end,
+		DurationSeconds: end.Sub(start).Seconds(),
+		Jobs:            make([]ReportResult, len(results)),
+	}
+
+	for i, result := range results {
+		reportResult := ReportResult{
+			Source:          result.Job.source.String(),
+			Destination:     result.Job.destination.String(),
+			Size:            result.Bytes,
+			ETag:            result.ETag,
+			VersionID:       result.VersionID,
+			ChecksumCRC32C:  result.Checksum,
+			DurationSeconds: result.Duration.Seconds(),
+			Status:          StatusSucceeded,
+		}
+
+		if !result.Start.IsZero() {
+			start := result.Start
+			reportResult.Start = &start
+		}
+
+		if result.Err != nil {
+			reportResult.Error = result.Err.Error()
+			// Jobs that never started were skipped because the copy was cancelled
+			if result.Start.IsZero() {
+				reportResult.Status = StatusSkipped
+				report.Totals.Skipped++
+			} else {
+				reportResult.Status = StatusFailed
+				report.Totals.Failed++
+			}
+		} else {
+			report.Totals.Succeeded++
+			report.Totals.Bytes += result.Bytes
+		}
+
+		report.Jobs[i] = reportResult
+	}
+	report.Totals.Jobs = len(results)
+
+	return report
+}
+
+// WriteReport writes a Report as JSON to a file
+func WriteReport(path string, report Report) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,52 @@
+package s3utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestNewReportStatuses(t *testing.T) {
+	job := NewCopyJob(localPath{raw: "src", size: -1}, localPath{raw: "dest", size: -1})
+	start := time.Now()
+	results := []CopyResult{
+		{ObjectInfo: ObjectInfo{Bytes: 10, ETag: "etag"}, Job: job, Start: start},
+		{Job: job, Start: start, Err: errors.New("failed")},
+		{Job: job, Err: context.Canceled},
+	}
+
+	report := NewReport(start, results)
+
+	expectedStatuses := []string{StatusSucceeded, StatusFailed, StatusSkipped}
+	for i, expected := range expectedStatuses {
+		if report.Jobs[i].Status != expected {
+			t.Errorf("expected job %d to have status %s but it had %s", i, expected, report.Jobs[i].Status)
+		}
+	}
+
+	expectedTotals := ReportTotals{Jobs: 3, Succeeded: 1, Failed: 1, Skipped: 1, Bytes: 10}
+	if report.Totals != expectedTotals {
+		t.Errorf("expected totals %+v but got %+v", expectedTotals, report.Totals)
+	}
+}
+
+func TestNewReportETags(t *testing.T) {
+	job := NewCopyJob(localPath{raw: "src", size: -1}, localPath{raw: "dest", size: -1})
+	start := time.Now()
+	results := []CopyResult{
+		{ObjectInfo: objectInfoFromAttributes(&s3.GetObjectAttributesOutput{ETag: aws.String("etag")}), Job: job, Start: start},
+		{ObjectInfo: ObjectInfo{ETag: unquoteETag(aws.String(`"etag"`))}, Job: job, Start: start},
+	}
+
+	report := NewReport(start, results)
+
+	for i, result := range report.Jobs {
+		if result.ETag != "etag" {
+			t.Errorf("expected job %d to have etag etag but it had %s", i, result.ETag)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package s3utils
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// findIncompleteUpload finds the most recently initiated incomplete multipart upload of a key
+func (c *Copier) findIncompleteUpload(ctx context.Context, bucket string, key string) (*types.MultipartUpload, error) {
+	var latest *types.MultipartUpload
+	input := s3.ListMultipartUploadsInput{
+		Bucket: &bucket,
+		Prefix: &key,
+	}
+
+	for {
+		res, err := c.Client.ListMultipartUploads(ctx, &input)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, upload := range res.Uploads {
+			if aws.ToString(upload.Key) != key {
+				continue
+			}
+			if latest == nil || aws.ToTime(upload.Initiated).After(aws.ToTime(latest.Initiated)) {
+				latest = &res.Uploads[i]
+			}
+		}
+
+		if !res.IsTruncated {
+			return latest, nil
+		}
+		input.KeyMarker = res.NextKeyMarker
+		input.UploadIdMarker = res.NextUploadIdMarker
+	}
+}
+
+// listUploadedParts lists the parts already uploaded to a multipart upload by part number
+func (c *Copier) listUploadedParts(ctx context.Context, bucket string, key string, uploadID *string) (map[int32]types.Part, error) {
+	parts := map[int32]types.Part{}
+	input := s3.ListPartsInput{
+		Bucket:   &bucket,
+		Key:      &key,
+		UploadId: uploadID,
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	paginator := s3.NewListPartsPaginator(c.Client, &input)
+
+	for paginator.HasMorePages() {
+		res, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range res.Parts {
+			parts[part.PartNumber] = part
+		}
+	}
+
+	return parts, nil
+}
+
+// partMatches checks whether an uploaded part has the same contents as a section of a local file
+func partMatches(part types.Part, section *io.SectionReader) (bool, error) {
+	if part.Size != section.Size() {
+		return false, nil
+	}
+
+	// The ETag of an encrypted part is not its md5 so prefer the checksum
+	if part.ChecksumCRC32C != nil {
+		h := crc32.New(crc32cTable)
+		_, err := io.Copy(h, section)
+		return base64.StdEncoding.EncodeToString(h.Sum(nil)) == *part.ChecksumCRC32C, err
+	}
+
+	etag := unquoteETag(part.ETag)
+	if _, err := hex.DecodeString(etag); err == nil && len(etag) == md5.Size*2 {
+		h := md5.New()
+		_, err := io.Copy(h, section)
+		return hex.EncodeToString(h.Sum(nil)) == etag, err
+	}
+
+	// A part with no digest to compare may have changed without changing size
+	//   so it is uploaded again
+	return false, nil
+}
+
+// uploadedPartSize infers the part size of an incomplete upload of a file of
+// size bytes from its uploaded parts. Every part but the last has the part
+// size, so any part with a higher numbered part after it has the part size.
+// A lone part could also be the short last part, unless it is the first
+// part, ends exactly at the end of the file, or no part size would make it
+// the last part. It returns false if the part size can't be determined.
+func uploadedPartSize(parts map[int32]types.Part, size int64) (int64, bool) {
+	var last int32 = 0
+	for partNumber := range parts {
+		if partNumber > last {
+			last = partNumber
+		}
+	}
+	if last == 0 {
+		return 0, false
+	}
+
+	var partSize int64 = 0
+	for partNumber, part := range parts {
+		if partNumber == last {
+			continue
+		}
+		if partSize != 0 && part.Size != partSize {
+			return 0, false
+		}
+		partSize = part.Size
+	}
+	if partSize != 0 {
+		return partSize, true
+	}
+
+	lone := parts[last].Size
+	before := int64(last - 1)
+	rest := size - lone
+	if before > 0 && lone*int64(last) != size && rest > 0 && rest%before == 0 && rest/before > lone {
+		return 0, false
+	}
+	return lone, true
+}
+
+// resumeUpload finishes an incomplete multipart upload of a file, only uploading parts
+// that are missing or do not match the local file. It returns nil if there was no
+// incomplete upload to resume.
+func (c *Copier) resumeUpload(ctx context.Context, file *os.File, uploadInput s3.PutObjectInput) (*s3.CompleteMultipartUploadOutput, error) {
+	bucket := *uploadInput.Bucket
+	key := *uploadInput.Key
+
+	upload, err := c.findIncompleteUpload(ctx, bucket, key)
+	if err != nil || upload == nil {
+		return nil, err
+	}
+
+	uploadedParts, err := c.listUploadedParts(ctx, bucket, key, upload.UploadId)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := stat.Size()
+	if size == 0 {
+		return nil, nil
+	}
+
+	// The parts must be the same size as the original upload's parts
+	partSize := multipartPartSize(c.partSize(size), size)
+	if len(uploadedParts) > 0 {
+		var ok bool
+		partSize, ok = uploadedPartSize(uploadedParts, size)
+		if !ok {
+			return nil, fmt.Errorf("cannot resume upload %s of %s: the part size can't be determined from its uploaded parts, rerun without --resume to start over", *upload.UploadId, key)
+		}
+	}
+
+	numParts := int32((size + partSize - 1) / partSize)
+	if numParts > manager.MaxUploadParts {
+		return nil, fmt.Errorf("cannot resume upload %s of %s: %d parts of %d bytes exceeds the part limit", *upload.UploadId, key, numParts, partSize)
+	}
+	if numParts > 1 && partSize < manager.MinUploadPartSize {
+		return nil, fmt.Errorf("cannot resume upload %s of %s: parts of %d bytes are smaller than the minimum part size, rerun without --resume to start over", *upload.UploadId, key, partSize)
+	}
+
+	partNumbers := make(chan int32, numParts)
+	for i := int32(1); i <= numParts; i++ {
+		partNumbers <- i
+	}
+	close(partNumbers)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	completedParts := make([]types.CompletedPart, 0, numParts)
+	for w := 0; w < c.Options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range partNumbers {
+				start := int64(partNumber-1) * partSize
+				length := partSize
+				if start+length > size {
+					length = size - start
+				}
+
+				completedPart, partErr := c.resumePart(ctx, file, uploadInput, upload.UploadId, uploadedParts, partNumber, start, length)
+
+				mutex.Lock()
+				if partErr != nil {
+					err = partErr
+				} else {
+					completedParts = append(completedParts, completedPart)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err != nil {
+		return nil, fmt.Errorf("%s (rerun with --resume to upload the remaining parts)", err)
+	}
+
+	sort.Slice(completedParts, func(i, j int) bool {
+		return completedParts[i].PartNumber < completedParts[j].PartNumber
+	})
+
+	return c.Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:               &bucket,
+		Key:                  &key,
+		UploadId:             upload.UploadId,
+		MultipartUpload:      &types.CompletedMultipartUpload{Parts: completedParts},
+		SSECustomerAlgorithm: uploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:       uploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:    uploadInput.SSECustomerKeyMD5,
+	})
+}
+
+// resumePart reuses an uploaded part if it matches the local file or uploads it otherwise
+func (c *Copier) resumePart(ctx context.Context, file *os.File, uploadInput s3.PutObjectInput, uploadID *string, uploadedParts map[int32]types.Part, partNumber int32, start int64, length int64) (types.CompletedPart, error) {
+	if part, ok := uploadedParts[partNumber]; ok {
+		matches, err := partMatches(part, io.NewSectionReader(file, start, length))
+		if err != nil {
+			return types.CompletedPart{}, err
+		}
+		if matches {
+			c.Progress.AddBytes(length)
+			return types.CompletedPart{
+				ETag:           part.ETag,
+				ChecksumCRC32C: part.ChecksumCRC32C,
+				PartNumber:     partNumber,
+			}, nil
+		}
+	}
+
+	resp, err := c.Client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:               uploadInput.Bucket,
+		Key:                  uploadInput.Key,
+		UploadId:             uploadID,
+		PartNumber:           partNumber,
+		Body:                 io.NewSectionReader(file, start, length),
+		ChecksumAlgorithm:    uploadInput.ChecksumAlgorithm,
+		SSECustomerAlgorithm: uploadInput.SSECustomerAlgorithm,
+		SSECustomerKey:       uploadInput.SSECustomerKey,
+		SSECustomerKeyMD5:    uploadInput.SSECustomerKeyMD5,
+	}, c.Uploader.ClientOptions...)
+	if err != nil {
+		return types.CompletedPart{}, err
+	}
+
+	return types.CompletedPart{
+		ETag:           resp.ETag,
+		ChecksumCRC32C: resp.ChecksumCRC32C,
+		PartNumber:     partNumber,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package s3utils
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestPartMatches(t *testing.T) {
+	contents := "part contents"
+	md5Sum := md5.Sum([]byte(contents))
+	etag := "\"" + hex.EncodeToString(md5Sum[:]) + "\""
+	crc := crc32.New(crc32cTable)
+	crc.Write([]byte(contents))
+	checksum := base64.StdEncoding.EncodeToString(crc.Sum(nil))
+
+	cases := []struct {
+		name     string
+		part     types.Part
+		section  string
+		expected bool
+	}{
+		{"md5 etag", types.Part{Size: 13, ETag: aws.String(etag)}, contents, true},
+		{"md5 etag changed", types.Part{Size: 13, ETag: aws.String(etag)}, "part CONTENTS", false},
+		{"size changed", types.Part{Size: 12, ETag: aws.String(etag)}, contents, false},
+		{"checksum", types.Part{Size: 13, ETag: aws.String("\"kms\""), ChecksumCRC32C: aws.String(checksum)}, contents, true},
+		{"checksum changed", types.Part{Size: 13, ETag: aws.String(etag), ChecksumCRC32C: aws.String(checksum)}, "part CONTENTS", false},
+		{"no digest", types.Part{Size: 13, ETag: aws.String("\"kms-etag\"")}, contents, false},
+	}
+
+	for _, c := range cases {
+		section := io.NewSectionReader(strings.NewReader(c.section), 0, int64(len(c.section)))
+		actual, err := partMatches(c.part, section)
+		if err != nil {
+			t.Fatalf("%s: encountered error while matching part: %s", c.name, err)
+		}
+		if actual != c.expected {
+			t.Errorf("%s: expected part to match %t but got %t", c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestUploadedPartSize(t *testing.T) {
+	parts := func(sizes map[int32]int64) map[int32]types.Part {
+		result := map[int32]types.Part{}
+		for partNumber, size := range sizes {
+			result[partNumber] = types.Part{PartNumber: partNumber, Size: size}
+		}
+		return result
+	}
+
+	cases := []struct {
+		name     string
+		parts    map[int32]types.Part
+		size     int64
+		expected int64
+		ok       bool
+	}{
+		{"none", parts(map[int32]int64{}), 100, 0, false},
+		{"followed part", parts(map[int32]int64{1: 40, 3: 20}), 100, 40, true},
+		{"inconsistent parts", parts(map[int32]int64{1: 40, 2: 30, 3: 20}), 100, 0, false},
+		{"lone first part", parts(map[int32]int64{1: 40}), 100, 40, true},
+		{"lone part ending the file", parts(map[int32]int64{4: 25}), 100, 25, true},
+		{"lone part that can't be last", parts(map[int32]int64{3: 30}), 101, 30, true},
+		{"lone part that could be last", parts(map[int32]int64{3: 20}), 100, 0, false},
+	}
+
+	for _, c := range cases {
+		actual, ok := uploadedPartSize(c.parts, c.size)
+		if ok != c.ok || actual != c.expected {
+			t.Errorf("%s: expected part size %d (%t) but got %d (%t)", c.name, c.expected, c.ok, actual, ok)
+		}
+	}
+}
@@ -3,8 +3,13 @@ package s3utils
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
 	"path"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
@@ -13,16 +18,35 @@ type s3Path struct {
 	bucket string
 	prefix string
 	raw    string
-	client *s3.Client
+	// versionID is the version of the object, empty for the latest version
+	versionID string
+	size      int64
+	modTime   time.Time
+	client    *s3.Client
+	// encryption holds the SSE-C key needed to head encrypted objects, if any
+	encryption *Encryption
+}
+
+// optionalVersionID gets a version ID to request, nil for the latest version
+func optionalVersionID(versionID string) *string {
+	if versionID == "" {
+		return nil
+	}
+	return &versionID
 }
 
 // IsDir Checks if a s3Path is a directory
-func (p s3Path) IsDir() (bool, error) {
+func (p s3Path) IsDir(ctx context.Context) (bool, error) {
 	// Consider the bucket alone as a directory
 	if p.prefix == "" {
 		return true, nil
 	}
 
+	// Only objects have versions
+	if p.versionID != "" {
+		return false, nil
+	}
+
 	// Paths with a trailing slash must be directories because creating
 	//   an object with a trailing slash doesn't work
 	if p.raw[len(p.raw)-1] == '/' {
@@ -39,7 +63,7 @@ func (p s3Path) IsDir() (bool, error) {
 		Prefix:  &prefix,
 		MaxKeys: maxKeys,
 	}
-	res, err := p.client.ListObjectsV2(context.Background(), &request)
+	res, err := p.client.ListObjectsV2(ctx, &request)
 	if err != nil {
 		return false, err
 	}
@@ -53,16 +77,20 @@ func (p s3Path) IsDir() (bool, error) {
 }
 
 // Exists Checks if a s3Path exists as an object or a folder
-func (p s3Path) Exists() (bool, error) {
+func (p s3Path) Exists(ctx context.Context) (bool, error) {
 	// The bucket alone exists
 	if p.prefix == "" {
 		return true, nil
 	}
 
-	_, err := p.client.HeadObject(context.Background(), &s3.HeadObjectInput{
-		Bucket: &p.bucket,
-		Key:    &p.prefix,
-	})
+	input := s3.HeadObjectInput{
+		Bucket:    &p.bucket,
+		Key:       &p.prefix,
+		VersionId: optionalVersionID(p.versionID),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = p.encryption.customerKeyHeaders()
+
+	_, err := p.client.HeadObject(ctx, &input)
 
 	var notFound *http.ResponseError
 	if err != nil && errors.As(err, &notFound) && notFound.HTTPStatusCode() == 404 {
@@ -92,49 +120,95 @@ func (p s3Path) FileOrObject() string {
 	return "object"
 }
 
-// ListPathsWithPrefix lists all paths with the s3Path as a prefix
-func (p s3Path) ListPathsWithPrefix() ([]Path, error) {
-	res, err := p.client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+// ListPathsWithPrefix sends all paths with the s3Path as a prefix to paths
+// as each page of the listing arrives
+func (p s3Path) ListPathsWithPrefix(ctx context.Context, paths chan<- Path) error {
+	if p.versionID != "" {
+		return p.listVersion(ctx, paths)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(p.client, &s3.ListObjectsV2Input{
 		Bucket: &p.bucket,
 		Prefix: &p.prefix,
 	})
 
-	if err != nil {
-		return []Path{}, err
+	// Add trailing / to the prefix to avoid partial matches, every key in the
+	//   bucket matches an empty prefix
+	prefixDir := ""
+	if p.prefix != "" {
+		prefixDir = addTrailingSlash(p.prefix)
 	}
 
-	// Add trailing / to the prefix to avoid partial matches
-	prefixDir := addTrailingSlash(p.prefix)
-
-	objects := res.Contents
-	paths := []Path{}
-	for _, object := range objects {
-		key := *object.Key
-		if key[len(key)-1] != '/' {
-			currentPath := s3Path{
-				bucket: p.bucket,
-				prefix: key,
-				raw:    bucketAndKeyToS3Path(p.bucket, key),
-				client: p.client,
-			}
-			if key == p.prefix {
-				return []Path{currentPath}, nil
-			}
-			if key[:len(prefixDir)] == prefixDir {
-				paths = append(paths, currentPath)
+	for paginator.HasMorePages() {
+		res, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range res.Contents {
+			key := *object.Key
+			if key[len(key)-1] != '/' {
+				currentPath := s3Path{
+					bucket:     p.bucket,
+					prefix:     key,
+					raw:        bucketAndKeyToS3Path(p.bucket, key),
+					size:       object.Size,
+					modTime:    aws.ToTime(object.LastModified),
+					client:     p.client,
+					encryption: p.encryption,
+				}
+				// Keys are listed in lexicographic order so an exact match
+				//   is always the first key listed
+				if p.prefix != "" && key == p.prefix {
+					return sendPath(ctx, paths, currentPath)
+				}
+				if strings.HasPrefix(key, prefixDir) {
+					err = sendPath(ctx, paths, currentPath)
+					if err != nil {
+						return err
+					}
+				}
 			}
 		}
 	}
 
-	return paths, err
+	return nil
+}
+
+// listVersion sends the s3Path to paths if its version exists, listings only
+// include the latest version so it is looked up directly
+func (p s3Path) listVersion(ctx context.Context, paths chan<- Path) error {
+	input := s3.HeadObjectInput{
+		Bucket:    &p.bucket,
+		Key:       &p.prefix,
+		VersionId: &p.versionID,
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = p.encryption.customerKeyHeaders()
+
+	res, err := p.client.HeadObject(ctx, &input)
+
+	var notFound *http.ResponseError
+	if err != nil && errors.As(err, &notFound) && notFound.HTTPStatusCode() == 404 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	p.size = res.ContentLength
+	p.modTime = aws.ToTime(res.LastModified)
+	return sendPath(ctx, paths, p)
 }
 
 // Join joins suffixes to this path
 func (p s3Path) Join(suffixes ...string) Path {
-	rawJoinArgs := append([]string{p.raw}, suffixes...)
+	// Joining the raw path would collapse the // in s3://
 	prefixJoinArgs := append([]string{p.prefix}, suffixes...)
-	p.raw = path.Join(rawJoinArgs...)
 	p.prefix = path.Join(prefixJoinArgs...)
+	p.raw = bucketAndKeyToS3Path(p.bucket, p.prefix)
+	p.versionID = ""
+	p.size = -1
+	p.modTime = time.Time{}
 	return p
 }
 
@@ -148,11 +222,30 @@ func (p s3Path) Base() string {
 	return path.Base(p.raw)
 }
 
+// Size returns the size of the object if it is known from listing or -1 otherwise
+func (p s3Path) Size() int64 {
+	return p.size
+}
+
+// ModTime returns the last modified time of the object if it is known from
+// listing or the zero time otherwise
+func (p s3Path) ModTime() time.Time {
+	return p.modTime
+}
+
 // Bucket returns the s3 bucket of this path
 func (p s3Path) Bucket() (string, error) {
 	return p.bucket, nil
 }
 
+// VersionID returns the version of the object, empty for the latest version
+func (p s3Path) VersionID() string {
+	return p.versionID
+}
+
 func (p s3Path) String() string {
+	if p.versionID != "" {
+		return fmt.Sprintf("%s?versionId=%s", p.raw, url.QueryEscape(p.versionID))
+	}
 	return p.raw
 }
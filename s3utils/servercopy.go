@@ -0,0 +1,282 @@
+package s3utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// maxCopyObjectSize is the largest object that can be copied with a single CopyObject call
+const maxCopyObjectSize int64 = 1024 * 1024 * 1024 * 5
+
+// copySource formats a bucket, key and optional version ID as the url encoded CopySource of a copy request
+func copySource(bucket string, key string, versionID string) string {
+	source := url.URL{Path: fmt.Sprintf("%s/%s", bucket, key)}
+	if versionID != "" {
+		return fmt.Sprintf("%s?versionId=%s", source.EscapedPath(), url.QueryEscape(versionID))
+	}
+	return source.EscapedPath()
+}
+
+// multipartPartSize picks a part size for a multipart upload or copy that respects s3's part size and count limits
+func multipartPartSize(partSize int64, objectSize int64) int64 {
+	if partSize < manager.MinUploadPartSize {
+		partSize = manager.MinUploadPartSize
+	}
+	if partSize > maxPartSize {
+		partSize = maxPartSize
+	}
+
+	if objectSize/partSize >= int64(manager.MaxUploadParts) {
+		partSize = objectSize/int64(manager.MaxUploadParts) + 1
+	}
+
+	return partSize
+}
+
+// sourceTagging gets the tags of an object formatted for an upload, nil if it has none
+func (c *Copier) sourceTagging(ctx context.Context, bucket string, key string, versionID string) (*string, error) {
+	resp, err := c.Client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: optionalVersionID(versionID),
+	})
+	if err != nil || len(resp.TagSet) == 0 {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(resp.TagSet))
+	for _, tag := range resp.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	tagging := objectTagging(tags)
+	return &tagging, nil
+}
+
+// serverSideCopy copies an object between s3 locations without downloading it
+func (c *Copier) serverSideCopy(ctx context.Context, srcBucket string, srcKey string, srcVersionID string, destBucket string, destKey string) (ObjectInfo, error) {
+	headObjectInput := s3.HeadObjectInput{
+		Bucket:    &srcBucket,
+		Key:       &srcKey,
+		VersionId: optionalVersionID(srcVersionID),
+	}
+	headObjectInput.SSECustomerAlgorithm, headObjectInput.SSECustomerKey, headObjectInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	headObjectResp, err := c.Client.HeadObject(ctx, &headObjectInput)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	size := headObjectResp.ContentLength
+	source := copySource(srcBucket, srcKey, srcVersionID)
+	storageClass := c.Options.StorageClasses.ForSize(size)
+
+	// Without a --part-size, objects CopyObject can copy in one request are
+	//   never split into parts
+	headers := c.copyHeaders(headObjectResp)
+
+	partSize := multipartPartSize(c.partSize(size), size)
+	if size <= maxCopyObjectSize && (c.Options.PartSize <= 0 || size <= partSize) {
+		copyObjectInput := s3.CopyObjectInput{
+			Bucket:       &destBucket,
+			Key:          &destKey,
+			CopySource:   &source,
+			StorageClass: storageClass,
+		}
+		copyObjectInput.ServerSideEncryption, copyObjectInput.SSEKMSKeyId = c.Options.Encryption.serverSide()
+		copyObjectInput.SSECustomerAlgorithm, copyObjectInput.SSECustomerKey, copyObjectInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+		copyObjectInput.CopySourceSSECustomerAlgorithm, copyObjectInput.CopySourceSSECustomerKey, copyObjectInput.CopySourceSSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+		// CopyObject copies the source's metadata and tags unless told to
+		//   replace them, in which case everything is replaced at once
+		if c.replacesMetadata() {
+			copyObjectInput.MetadataDirective = types.MetadataDirectiveReplace
+			copyObjectInput.Metadata = headers.Metadata
+			copyObjectInput.CacheControl = headers.CacheControl
+			copyObjectInput.ContentDisposition = headers.ContentDisposition
+			copyObjectInput.ContentEncoding = headers.ContentEncoding
+			copyObjectInput.ContentLanguage = headers.ContentLanguage
+			copyObjectInput.ContentType = headers.ContentType
+		}
+		if headers.Tagging != nil {
+			copyObjectInput.TaggingDirective = types.TaggingDirectiveReplace
+			copyObjectInput.Tagging = headers.Tagging
+		}
+
+		if c.Options.Checksum {
+			copyObjectInput.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32c
+		}
+
+		copyObjectResp, err := c.Client.CopyObject(ctx, &copyObjectInput)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+
+		c.Progress.AddBytes(size)
+		return ObjectInfo{
+			Bytes:     size,
+			ETag:      unquoteETag(copyObjectResp.CopyObjectResult.ETag),
+			VersionID: aws.ToString(copyObjectResp.VersionId),
+			Checksum:  aws.ToString(copyObjectResp.CopyObjectResult.ChecksumCRC32C),
+		}, nil
+	}
+
+	// Unlike CopyObject, a multipart copy does not carry over the source's
+	//   metadata or tags so they must be set when the upload is created
+	if headers.Tagging == nil {
+		headers.Tagging, err = c.sourceTagging(ctx, srcBucket, srcKey, srcVersionID)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+	createMultipartUploadInput := s3.CreateMultipartUploadInput{
+		Bucket:             &destBucket,
+		Key:                &destKey,
+		Metadata:           headers.Metadata,
+		CacheControl:       headers.CacheControl,
+		ContentDisposition: headers.ContentDisposition,
+		ContentEncoding:    headers.ContentEncoding,
+		ContentLanguage:    headers.ContentLanguage,
+		ContentType:        headers.ContentType,
+		Tagging:            headers.Tagging,
+		StorageClass:       storageClass,
+	}
+	createMultipartUploadInput.ServerSideEncryption, createMultipartUploadInput.SSEKMSKeyId = c.Options.Encryption.serverSide()
+	createMultipartUploadInput.SSECustomerAlgorithm, createMultipartUploadInput.SSECustomerKey, createMultipartUploadInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	if c.Options.Checksum {
+		createMultipartUploadInput.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32c
+	}
+
+	createMultipartUploadResp, err := c.Client.CreateMultipartUpload(ctx, &createMultipartUploadInput)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	uploadID := createMultipartUploadResp.UploadId
+	completedParts, err := c.copyParts(ctx, source, destBucket, destKey, uploadID, size, partSize)
+	if err != nil {
+		_, abortErr := c.Client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   &destBucket,
+			Key:      &destKey,
+			UploadId: uploadID,
+		})
+		if abortErr != nil {
+			return ObjectInfo{}, fmt.Errorf("%s (aborting multipart upload %s also failed: %s)", err, *uploadID, abortErr)
+		}
+		return ObjectInfo{}, err
+	}
+
+	completeMultipartUploadInput := s3.CompleteMultipartUploadInput{
+		Bucket:          &destBucket,
+		Key:             &destKey,
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	}
+	completeMultipartUploadInput.SSECustomerAlgorithm, completeMultipartUploadInput.SSECustomerKey, completeMultipartUploadInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	completeResp, err := c.Client.CompleteMultipartUpload(ctx, &completeMultipartUploadInput)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	c.Progress.AddBytes(size)
+	return ObjectInfo{
+		Bytes:     size,
+		ETag:      unquoteETag(completeResp.ETag),
+		VersionID: aws.ToString(completeResp.VersionId),
+		Checksum:  aws.ToString(completeResp.ChecksumCRC32C),
+	}, nil
+}
+
+// copyParts copies the parts of a multipart copy in parallel, stopping at the
+// first part that fails
+func (c *Copier) copyParts(ctx context.Context, source string, destBucket string, destKey string, uploadID *string, size int64, partSize int64) ([]types.CompletedPart, error) {
+	numParts := int((size + partSize - 1) / partSize)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	partNumbers := make(chan int32, numParts)
+	for i := 1; i <= numParts; i++ {
+		partNumbers <- int32(i)
+	}
+	close(partNumbers)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var err error
+	completedParts := make([]types.CompletedPart, 0, numParts)
+
+	for w := 0; w < c.Options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partNumber := range partNumbers {
+				if ctx.Err() != nil {
+					return
+				}
+
+				start := int64(partNumber-1) * partSize
+				end := start + partSize - 1
+				if end >= size {
+					end = size - 1
+				}
+				copySourceRange := fmt.Sprintf("bytes=%d-%d", start, end)
+
+				uploadPartCopyInput := s3.UploadPartCopyInput{
+					Bucket:          &destBucket,
+					Key:             &destKey,
+					CopySource:      &source,
+					CopySourceRange: &copySourceRange,
+					PartNumber:      partNumber,
+					UploadId:        uploadID,
+				}
+				uploadPartCopyInput.SSECustomerAlgorithm, uploadPartCopyInput.SSECustomerKey, uploadPartCopyInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+				uploadPartCopyInput.CopySourceSSECustomerAlgorithm, uploadPartCopyInput.CopySourceSSECustomerKey, uploadPartCopyInput.CopySourceSSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+				// Part copies don't go through the uploader so take their request slots directly
+				partErr := c.budget.acquireRequest(ctx)
+				var resp *s3.UploadPartCopyOutput
+				if partErr == nil {
+					resp, partErr = c.Client.UploadPartCopy(ctx, &uploadPartCopyInput)
+					c.budget.releaseRequest()
+				}
+
+				mutex.Lock()
+				if partErr != nil {
+					// Keep the first error rather than the cancellations it causes
+					if err == nil {
+						err = partErr
+						cancel()
+					}
+				} else {
+					completedParts = append(completedParts, types.CompletedPart{
+						ETag:           resp.CopyPartResult.ETag,
+						ChecksumCRC32C: resp.CopyPartResult.ChecksumCRC32C,
+						PartNumber:     partNumber,
+					})
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(completedParts, func(i, j int) bool {
+		return completedParts[i].PartNumber < completedParts[j].PartNumber
+	})
+
+	return completedParts, nil
+}
@@ -0,0 +1,154 @@
+package s3utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestCopySource(t *testing.T) {
+	cases := []struct {
+		bucket   string
+		key      string
+		expected string
+	}{
+		{"bucket", "object", "bucket/object"},
+		{"bucket", "dir/object", "bucket/dir/object"},
+		{"bucket", "dir/my object", "bucket/dir/my%20object"},
+		{"bucket", "a+b?c#d", "bucket/a+b%3Fc%23d"},
+	}
+
+	for _, c := range cases {
+		if actual := copySource(c.bucket, c.key, ""); actual != c.expected {
+			t.Errorf("expected copy source %s for %s/%s but got %s", c.expected, c.bucket, c.key, actual)
+		}
+	}
+}
+
+func TestMultipartPartSize(t *testing.T) {
+	const mib = 1024 * 1024
+	maxParts := int64(manager.MaxUploadParts)
+	cases := []struct {
+		partSize   int64
+		objectSize int64
+		expected   int64
+	}{
+		{mib, 100 * mib, manager.MinUploadPartSize},
+		{8 * mib, 100 * mib, 8 * mib},
+		{8 * mib, 8 * mib * (maxParts - 1), 8 * mib},
+		{8 * mib, 8 * mib * maxParts, 8*mib + 1},
+		{mib, 5 * mib * maxParts * 2, 10*mib + 1},
+		{maxPartSize * 2, maxPartSize * 2, maxPartSize},
+	}
+
+	for _, c := range cases {
+		actual := multipartPartSize(c.partSize, c.objectSize)
+		if actual != c.expected {
+			t.Errorf("expected part size %d for %d byte parts of %d bytes but got %d", c.expected, c.partSize, c.objectSize, actual)
+		}
+		if (c.objectSize+actual-1)/actual > maxParts {
+			t.Errorf("expected part size %d for %d bytes to be within s3's part count limit", actual, c.objectSize)
+		}
+	}
+}
+
+func TestCopyPartsStopsAtFirstFailure(t *testing.T) {
+	var requests int32 = 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-west-2",
+		Credentials:      aws.AnonymousCredentials{},
+		EndpointResolver: s3.EndpointResolverFromURL(server.URL),
+		UsePathStyle:     true,
+		RetryMaxAttempts: 1,
+	})
+	copier := NewCopier(CopierOptions{Concurrency: 1}, client)
+
+	const partSize = 5 * 1024 * 1024
+	_, err := copier.copyParts(context.Background(), "bucket/src", "bucket", "dest", aws.String("upload"), 3*partSize, partSize)
+	if err == nil {
+		t.Fatalf("expected the copy to fail")
+	}
+	if requests != 1 {
+		t.Errorf("expected the remaining parts to be skipped after the first failure but %d were copied", requests)
+	}
+}
+
+func TestServerSideCopyTags(t *testing.T) {
+	const partSize = 5 * 1024 * 1024
+	var size int64
+	var mutex sync.Mutex
+	tagging := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Length", fmt.Sprint(size))
+		case r.Method == http.MethodGet && query.Has("tagging"):
+			w.Write([]byte("<Tagging><TagSet><Tag><Key>source</Key><Value>tag</Value></Tag></TagSet></Tagging>"))
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			mutex.Lock()
+			tagging["CreateMultipartUpload"] = r.Header.Get("X-Amz-Tagging")
+			mutex.Unlock()
+			w.Write([]byte("<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>"))
+		case r.Method == http.MethodPut && query.Has("partNumber"):
+			w.Write([]byte("<CopyPartResult><ETag>\"part\"</ETag></CopyPartResult>"))
+		case r.Method == http.MethodPut:
+			mutex.Lock()
+			tagging["CopyObject"] = r.Header.Get("X-Amz-Tagging-Directive") + " " + r.Header.Get("X-Amz-Tagging")
+			mutex.Unlock()
+			w.Write([]byte("<CopyObjectResult><ETag>\"copy\"</ETag></CopyObjectResult>"))
+		default:
+			w.Write([]byte("<CompleteMultipartUploadResult><ETag>\"multipart\"</ETag></CompleteMultipartUploadResult>"))
+		}
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-west-2",
+		Credentials:      aws.AnonymousCredentials{},
+		EndpointResolver: s3.EndpointResolverFromURL(server.URL),
+		UsePathStyle:     true,
+		RetryMaxAttempts: 1,
+	})
+
+	cases := []struct {
+		tags      map[string]string
+		size      int64
+		operation string
+		expected  string
+	}{
+		// CopyObject copies the source's tags itself
+		{nil, 1, "CopyObject", " "},
+		{map[string]string{"new": "tag"}, 1, "CopyObject", "REPLACE new=tag"},
+		{nil, 2 * partSize, "CreateMultipartUpload", "source=tag"},
+		{map[string]string{"new": "tag"}, 2 * partSize, "CreateMultipartUpload", "new=tag"},
+	}
+
+	for _, c := range cases {
+		size = c.size
+		tagging = map[string]string{}
+		copier := NewCopier(CopierOptions{Concurrency: 1, PartSize: partSize, Tags: c.tags}, client)
+		if _, err := copier.serverSideCopy(context.Background(), "bucket", "src", "", "bucket", "dest"); err != nil {
+			t.Fatalf("encountered error while copying %d bytes: %s", c.size, err)
+		}
+
+		if actual, ok := tagging[c.operation]; !ok || actual != c.expected {
+			t.Errorf("expected %s of %d bytes with tags %v to be tagged %q but got %v", c.operation, c.size, c.tags, c.expected, tagging)
+		}
+	}
+}
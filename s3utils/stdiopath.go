@@ -0,0 +1,94 @@
+package s3utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// stdioName is the path that refers to stdin as a source and stdout as a destination
+const stdioName = "-"
+
+// stdioPath is stdin when copying from it and stdout when copying to it
+type stdioPath struct{}
+
+// isStdio checks whether a Path is stdin or stdout
+func isStdio(p Path) bool {
+	_, ok := p.(stdioPath)
+	return ok
+}
+
+// IsDir is always false for a stdioPath
+func (p stdioPath) IsDir(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// Exists is always true for a stdioPath
+func (p stdioPath) Exists(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// IsS3 is always false for a stdioPath
+func (p stdioPath) IsS3() bool {
+	return false
+}
+
+// IsLocal is always false for a stdioPath
+func (p stdioPath) IsLocal() bool {
+	return false
+}
+
+// DirOrFolder returns "directory"
+func (p stdioPath) DirOrFolder() string {
+	return "directory"
+}
+
+// FileOrObject returns "stream"
+func (p stdioPath) FileOrObject() string {
+	return "stream"
+}
+
+// ListPathsWithPrefix sends the stdioPath itself
+func (p stdioPath) ListPathsWithPrefix(ctx context.Context, paths chan<- Path) error {
+	return sendPath(ctx, paths, p)
+}
+
+// Join returns the stdioPath itself since nothing can be under a stream
+func (p stdioPath) Join(suffixes ...string) Path {
+	return p
+}
+
+// Base returns "-"
+func (p stdioPath) Base() string {
+	return stdioName
+}
+
+// Size is always unknown for a stdioPath
+func (p stdioPath) Size() int64 {
+	return -1
+}
+
+// ModTime is always zero for a stdioPath
+func (p stdioPath) ModTime() time.Time {
+	return time.Time{}
+}
+
+// WithoutBucket returns "-"
+func (p stdioPath) WithoutBucket() string {
+	return stdioName
+}
+
+// Bucket returns an error since stdioPath has no bucket
+func (p stdioPath) Bucket() (string, error) {
+	return "", fmt.Errorf("requested bucket of non-s3 path: %s", p)
+}
+
+// VersionID is always empty for a stdioPath
+func (p stdioPath) VersionID() string {
+	return ""
+}
+
+// String returns "-"
+func (p stdioPath) String() string {
+	return stdioName
+}
@@ -0,0 +1,79 @@
+package s3utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// storageClassRule stores objects of at least minSize bytes in storageClass
+type storageClassRule struct {
+	minSize      int64
+	storageClass types.StorageClass
+}
+
+// StorageClasses picks the storage class of each uploaded or copied object
+// by its size. Objects use the storage class of the rule with the largest
+// minimum size they reach, or the default storage class if they reach none.
+// An empty storage class leaves the choice to the bucket.
+type StorageClasses struct {
+	defaultClass types.StorageClass
+	rules        []storageClassRule
+}
+
+// parseStorageClass checks that a storage class is one s3 knows
+func parseStorageClass(name string) (types.StorageClass, error) {
+	storageClass := types.StorageClass(strings.ToUpper(name))
+	for _, known := range storageClass.Values() {
+		if storageClass == known {
+			return storageClass, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid storage class %s", name)
+}
+
+// SetDefault sets the storage class of objects that reach no rule's minimum size
+func (s *StorageClasses) SetDefault(name string) error {
+	storageClass, err := parseStorageClass(name)
+	if err != nil {
+		return err
+	}
+
+	s.defaultClass = storageClass
+	return nil
+}
+
+// AddRule stores objects of at least minSize bytes in a storage class
+func (s *StorageClasses) AddRule(minSize int64, name string) error {
+	storageClass, err := parseStorageClass(name)
+	if err != nil {
+		return err
+	}
+
+	s.rules = append(s.rules, storageClassRule{minSize: minSize, storageClass: storageClass})
+	sort.SliceStable(s.rules, func(i, j int) bool {
+		return s.rules[i].minSize > s.rules[j].minSize
+	})
+	return nil
+}
+
+// ForSize gets the storage class of an object of size bytes, a negative
+// size is unknown and gets the default storage class
+func (s *StorageClasses) ForSize(size int64) types.StorageClass {
+	if s == nil {
+		return ""
+	}
+
+	if size >= 0 {
+		for _, rule := range s.rules {
+			if size >= rule.minSize {
+				return rule.storageClass
+			}
+		}
+	}
+
+	return s.defaultClass
+}
@@ -0,0 +1,51 @@
+package s3utils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestStorageClassesForSize(t *testing.T) {
+	storageClasses := StorageClasses{}
+	if err := storageClasses.SetDefault("standard"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storageClasses.AddRule(1024, "INTELLIGENT_TIERING"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storageClasses.AddRule(1024*1024, "GLACIER_IR"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		size     int64
+		expected types.StorageClass
+	}{
+		{-1, types.StorageClassStandard},
+		{0, types.StorageClassStandard},
+		{1023, types.StorageClassStandard},
+		{1024, types.StorageClassIntelligentTiering},
+		{1024 * 1024, types.StorageClassGlacierIr},
+	}
+
+	for _, c := range cases {
+		if storageClass := storageClasses.ForSize(c.size); storageClass != c.expected {
+			t.Errorf("expected storage class %s for size %d but got %s", c.expected, c.size, storageClass)
+		}
+	}
+}
+
+func TestStorageClassesNil(t *testing.T) {
+	var storageClasses *StorageClasses
+	if storageClass := storageClasses.ForSize(1024); storageClass != "" {
+		t.Errorf("expected no storage class but got %s", storageClass)
+	}
+}
+
+func TestStorageClassesInvalid(t *testing.T) {
+	storageClasses := StorageClasses{}
+	if err := storageClasses.SetDefault("COLD"); err == nil {
+		t.Errorf("expected COLD to be an invalid storage class")
+	}
+}
@@ -0,0 +1,269 @@
+package s3utils
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// pendingPart is a part buffered by an orderedWriter until the parts before it are written
+type pendingPart struct {
+	data    []byte
+	release func()
+}
+
+// orderedWriter reassembles parts that complete in any order into a
+// sequential stream. At most window parts past the last one written can be
+// buffered so memory stays bounded when an early part is slow.
+type orderedWriter struct {
+	w       io.Writer
+	window  int
+	next    int
+	pending map[int]pendingPart
+	err     error
+	mutex   sync.Mutex
+	cond    *sync.Cond
+}
+
+// newOrderedWriter creates an orderedWriter that writes to w
+func newOrderedWriter(w io.Writer, window int) *orderedWriter {
+	o := orderedWriter{
+		w:       w,
+		window:  window,
+		pending: map[int]pendingPart{},
+	}
+	o.cond = sync.NewCond(&o.mutex)
+	return &o
+}
+
+// wait blocks until a part can be buffered without exceeding the window
+func (o *orderedWriter) wait(part int) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for o.err == nil && part >= o.next+o.window {
+		o.cond.Wait()
+	}
+	return o.err
+}
+
+// write buffers a part and writes every buffered part that is next in order.
+// release, if not nil, is called once the part's data is no longer needed.
+func (o *orderedWriter) write(part int, data []byte, release func()) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.pending[part] = pendingPart{data: data, release: release}
+	if o.err != nil {
+		o.releasePending()
+		return o.err
+	}
+
+	for {
+		next, ok := o.pending[o.next]
+		if !ok {
+			break
+		}
+		delete(o.pending, o.next)
+
+		_, err := o.w.Write(next.data)
+		if next.release != nil {
+			next.release()
+		}
+		if err != nil {
+			o.err = err
+			o.releasePending()
+			break
+		}
+		o.next++
+	}
+
+	o.cond.Broadcast()
+	return o.err
+}
+
+// releasePending drops every buffered part once the writer has failed, the
+// mutex must be held
+func (o *orderedWriter) releasePending() {
+	for part, pending := range o.pending {
+		delete(o.pending, part)
+		if pending.release != nil {
+			pending.release()
+		}
+	}
+}
+
+// fail stops the writer, waking up any parts waiting to be buffered
+func (o *orderedWriter) fail(err error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.err == nil {
+		o.err = err
+	}
+	o.releasePending()
+	o.cond.Broadcast()
+}
+
+// downloadStream downloads an object to out with parallel ranged gets, writing
+// the ranges in order. With the Checksum option each part of a multipart
+// object is verified before it is written, other objects can only be
+// verified once all of their data has been written.
+func (c *Copier) downloadStream(ctx context.Context, bucket string, key string, versionID string, out io.Writer) (ObjectInfo, error) {
+	attributes, partSize, err := c.downloadAttributes(ctx, bucket, key, versionID)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	etag := aws.ToString(attributes.ETag)
+	getObjectInput := s3.GetObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: optionalVersionID(versionID),
+	}
+	getObjectInput.SSECustomerAlgorithm, getObjectInput.SSECustomerKey, getObjectInput.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	ranges := splitRange(0, attributes.ObjectSize-1, partSize)
+	partChecksums := []string{}
+	var objectHash hash.Hash = nil
+	if c.Options.Checksum {
+		getObjectInput.ChecksumMode = types.ChecksumModeEnabled
+
+		checksum, parts, err := c.listChecksumParts(ctx, bucket, key, versionID)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+		if checksum == nil || checksum.ChecksumCRC32C == nil {
+			return ObjectInfo{}, fmt.Errorf("s3://%s/%s has %w, it must be uploaded with --checksum to verify its checksum", bucket, key, errNoChecksum)
+		}
+
+		if len(parts) > 0 {
+			// Download the object's parts so each one can be verified on its own
+			ranges = []ByteRange{}
+			var start int64 = 0
+			for _, part := range parts {
+				ranges = append(ranges, ByteRange{Start: start, End: start + part.Size - 1})
+				partChecksums = append(partChecksums, aws.ToString(part.ChecksumCRC32C))
+				start += part.Size
+			}
+		} else {
+			objectHash = crc32.New(crc32cTable)
+			out = io.MultiWriter(out, objectHash)
+		}
+	}
+
+	writer := newOrderedWriter(out, c.Options.Concurrency)
+	err = c.getRanges(ctx, getObjectInput, &etag, ranges, func(i int, rangeInput *s3.GetObjectInput) error {
+		// Once a part has failed the remaining parts are skipped
+		if writer.wait(i) != nil {
+			return nil
+		}
+
+		// Parts hold a buffer from the budget until they are written out,
+		//   and the window keeps the parts holding one within the budget
+		//   so the next part to write always gets one
+		r := ranges[i]
+		release := c.budget.acquireBuffer()
+		buffer := manager.NewWriteAtBuffer(make([]byte, r.End-r.Start+1))
+		// The part is already buffered in memory so it doesn't take another
+		//   buffer from the Downloader's BufferProvider
+		_, err := c.Downloader.Download(ctx, buffer, rangeInput, func(d *manager.Downloader) {
+			d.BufferProvider = nil
+		})
+		if err == nil && len(partChecksums) > 0 {
+			h := crc32.New(crc32cTable)
+			h.Write(buffer.Bytes())
+			actual := base64.StdEncoding.EncodeToString(h.Sum(nil))
+			if actual != partChecksums[i] {
+				err = fmt.Errorf("%w for part %d of s3://%s/%s: expected %s but the downloaded part has %s", errChecksumMismatch, i+1, bucket, key, partChecksums[i], actual)
+			}
+		}
+		if err == nil {
+			err = writer.write(i, buffer.Bytes(), release)
+		} else {
+			release()
+		}
+		if err != nil {
+			writer.fail(err)
+		}
+		return err
+	})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	info := objectInfoFromAttributes(attributes)
+	if objectHash != nil {
+		actual := base64.StdEncoding.EncodeToString(objectHash.Sum(nil))
+		if actual != info.Checksum {
+			return ObjectInfo{}, fmt.Errorf("%w for s3://%s/%s: expected %s but the streamed data has %s", errChecksumMismatch, bucket, key, info.Checksum, actual)
+		}
+	}
+
+	return info, nil
+}
+
+// countingReader counts the bytes read from an io.Reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from the underlying reader and counts the bytes read
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// uploadStream uploads everything read from r to an object. Since the length
+// is not known ahead of time r is uploaded in parts of the PartSize option,
+// or 64 MiB without it, or with a single put if it is smaller than one part.
+func (c *Copier) uploadStream(ctx context.Context, r io.Reader, bucket string, key string) (ObjectInfo, error) {
+	body := &countingReader{r: r}
+	uploadInput := s3.PutObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   body,
+	}
+
+	if c.Options.Checksum {
+		uploadInput.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32c
+	}
+
+	// A stream has no extension to detect its Content-Type from
+	c.setUploadHeaders(&uploadInput, "")
+	c.setUploadEncryption(&uploadInput)
+	uploadInput.StorageClass = c.Options.StorageClasses.ForSize(-1)
+
+	// A stream can't be read again to resume its upload so never leave parts behind
+	uploadOutput, err := c.Uploader.Upload(ctx, &uploadInput, func(u *manager.Uploader) {
+		u.LeavePartsOnError = false
+		u.PartSize = multipartPartSize(c.partSize(-1), -1)
+		// Streams are read into part buffers the Uploader allocates itself,
+		//   up to one more than its concurrency, so upload one fewer part at
+		//   a time to keep them within the budget's buffers
+		if c.Options.Concurrency > 1 {
+			u.Concurrency = c.Options.Concurrency - 1
+		}
+	})
+	if err != nil {
+		return ObjectInfo{}, c.abortFailedUpload(ctx, err, bucket, key)
+	}
+
+	return ObjectInfo{
+		Bytes:     body.n,
+		ETag:      unquoteETag(uploadOutput.ETag),
+		VersionID: aws.ToString(uploadOutput.VersionID),
+		Checksum:  aws.ToString(uploadOutput.ChecksumCRC32C),
+	}, nil
+}
@@ -0,0 +1,84 @@
+package s3utils
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestOrderedWriterReordersParts(t *testing.T) {
+	var out bytes.Buffer
+	writer := newOrderedWriter(&out, 2)
+
+	parts := []string{"a", "bb", "ccc", "dddd", "e"}
+	order := []int{1, 0, 3, 2, 4}
+
+	var wg sync.WaitGroup
+	for _, i := range order {
+		// Parts past the window wait for earlier parts to be written
+		if err := writer.wait(i); err != nil {
+			t.Fatalf("encountered error while waiting for part %d: %s", i, err)
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writer.write(i, []byte(parts[i]), nil)
+		}(i)
+	}
+	wg.Wait()
+
+	if out.String() != "abbcccdddde" {
+		t.Errorf("expected the parts to be written in order but got %s", out.String())
+	}
+}
+
+func TestOrderedWriterFail(t *testing.T) {
+	var out bytes.Buffer
+	writer := newOrderedWriter(&out, 1)
+	expected := errors.New("failed")
+
+	done := make(chan error)
+	go func() {
+		done <- writer.wait(1)
+	}()
+
+	writer.fail(expected)
+	if err := <-done; err != expected {
+		t.Errorf("expected waiting parts to get error %s but got %v", expected, err)
+	}
+
+	if err := writer.write(0, []byte("a"), nil); err != expected {
+		t.Errorf("expected writes after failing to get error %s but got %v", expected, err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be written after failing but got %s", out.String())
+	}
+}
+
+func TestOrderedWriterReleasesParts(t *testing.T) {
+	var out bytes.Buffer
+	writer := newOrderedWriter(&out, 3)
+
+	released := map[int]bool{}
+	release := func(i int) func() {
+		return func() { released[i] = true }
+	}
+
+	writer.write(1, []byte("b"), release(1))
+	if released[1] {
+		t.Errorf("expected part 1 to be held until it is written")
+	}
+
+	writer.write(0, []byte("a"), release(0))
+	if !released[0] || !released[1] {
+		t.Errorf("expected parts 0 and 1 to be released once written but got %v", released)
+	}
+
+	writer.write(3, []byte("d"), release(3))
+	writer.fail(errors.New("failed"))
+	if !released[3] {
+		t.Errorf("expected buffered part 3 to be released once the writer failed")
+	}
+}
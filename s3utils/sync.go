@@ -0,0 +1,234 @@
+package s3utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// indexPaths lists every path under a path keyed by its cleaned path without the bucket
+func indexPaths(ctx context.Context, p Path) (map[string]Path, error) {
+	index := map[string]Path{}
+
+	// Listing a local path that doesn't exist fails but listing an s3 prefix
+	//   that doesn't exist just lists nothing
+	if p.IsLocal() {
+		exists, err := p.Exists(ctx)
+		if err != nil || !exists {
+			return index, err
+		}
+	}
+
+	paths := make(chan Path)
+	listErrors := make(chan error, 1)
+	go func() {
+		listErrors <- p.ListPathsWithPrefix(ctx, paths)
+		close(paths)
+	}()
+
+	for listedPath := range paths {
+		index[path.Clean(listedPath.WithoutBucket())] = listedPath
+	}
+
+	return index, <-listErrors
+}
+
+// SyncCopyJobs forwards the jobs received from copyJobs whose destination does
+// not already match their source to changed, closing changed when done. A
+// destination matches if it has the same size as the source and is at least
+// as new or, with the Checksum option, has the same size and crc32c checksum.
+// It returns the paths under dest that no job copies to, sorted.
+func (c *Copier) SyncCopyJobs(ctx context.Context, dest Path, copyJobs <-chan CopyJob, changed chan<- CopyJob) ([]Path, error) {
+	defer close(changed)
+
+	index, err := indexPaths(ctx, dest)
+	if err != nil {
+		// Keep draining the channel so the producer isn't blocked
+		for range copyJobs {
+		}
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < c.Options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for copyJob := range copyJobs {
+				key := path.Clean(copyJob.destination.WithoutBucket())
+				mutex.Lock()
+				seen[key] = true
+				failed := err != nil
+				mutex.Unlock()
+				if failed {
+					continue
+				}
+
+				unchanged := false
+				destPath, ok := index[key]
+				if ok {
+					var jobErr error
+					unchanged, jobErr = c.unchanged(ctx, copyJob.source, destPath)
+					if jobErr != nil {
+						mutex.Lock()
+						err = jobErr
+						mutex.Unlock()
+						continue
+					}
+				}
+
+				if !unchanged {
+					select {
+					case changed <- copyJob:
+					case <-ctx.Done():
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	extraneous := []Path{}
+	for key, destPath := range index {
+		if !seen[key] {
+			extraneous = append(extraneous, destPath)
+		}
+	}
+	sort.Slice(extraneous, func(i, j int) bool {
+		return extraneous[i].String() < extraneous[j].String()
+	})
+
+	return extraneous, nil
+}
+
+// unchanged checks whether a destination already matches its source
+func (c *Copier) unchanged(ctx context.Context, src Path, dest Path) (bool, error) {
+	if src.Size() < 0 || src.Size() != dest.Size() {
+		return false, nil
+	}
+
+	if !c.Options.Checksum {
+		// Downloads with --preserve get the mtime stored in the object's
+		//   metadata rather than its last modified time so compare to that
+		if c.Options.Preserve && src.IsS3() && dest.IsLocal() {
+			bucket, err := src.Bucket()
+			if err != nil {
+				return false, err
+			}
+			metadata, lastModified, err := c.objectAttributes(ctx, bucket, src.WithoutBucket(), src.VersionID())
+			if err != nil {
+				return false, err
+			}
+			return dest.ModTime().Unix() == metadataModTime(metadata, lastModified).Unix(), nil
+		}
+
+		return !dest.ModTime().Before(src.ModTime()), nil
+	}
+
+	if src.IsS3() && dest.IsS3() {
+		srcChecksum, err := c.objectChecksum(ctx, src)
+		if err != nil {
+			return false, err
+		}
+		destChecksum, err := c.objectChecksum(ctx, dest)
+		if err != nil {
+			return false, err
+		}
+		return srcChecksum != "" && srcChecksum == destChecksum, nil
+	}
+
+	if src.IsS3() {
+		return c.objectMatchesFile(ctx, src, dest.String())
+	}
+
+	if dest.IsS3() {
+		return c.objectMatchesFile(ctx, dest, src.String())
+	}
+
+	srcChecksum, err := crc32cOfFile(src.String())
+	if err != nil {
+		return false, err
+	}
+	destChecksum, err := crc32cOfFile(dest.String())
+	if err != nil {
+		return false, err
+	}
+	return srcChecksum == destChecksum, nil
+}
+
+// objectChecksum gets the crc32c checksum s3 stored for an object, if any
+func (c *Copier) objectChecksum(ctx context.Context, object Path) (string, error) {
+	bucket, err := object.Bucket()
+	if err != nil {
+		return "", err
+	}
+
+	key := object.WithoutBucket()
+	input := s3.GetObjectAttributesInput{
+		Bucket:           &bucket,
+		Key:              &key,
+		VersionId:        optionalVersionID(object.VersionID()),
+		ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesChecksum},
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = c.Options.Encryption.customerKeyHeaders()
+
+	res, err := c.Client.GetObjectAttributes(ctx, &input)
+	if err != nil || res.Checksum == nil {
+		return "", err
+	}
+
+	return aws.ToString(res.Checksum.ChecksumCRC32C), nil
+}
+
+// objectMatchesFile checks whether a local file has the crc32c checksum s3 stored for an object
+func (c *Copier) objectMatchesFile(ctx context.Context, object Path, filename string) (bool, error) {
+	bucket, err := object.Bucket()
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	err = c.verifyChecksum(ctx, bucket, object.WithoutBucket(), object.VersionID(), file)
+	if errors.Is(err, errChecksumMismatch) || errors.Is(err, errNoChecksum) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// crc32cOfFile computes the base64 encoded crc32c checksum of a local file
+func crc32cOfFile(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	return crc32cOfSection(file, 0, stat.Size())
+}
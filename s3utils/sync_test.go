@@ -0,0 +1,149 @@
+package s3utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func syncedDestinations(t *testing.T, copier Copier, src string, dest string) ([]string, []Path) {
+	srcPath, _ := NewPath(copier.Client, src)
+	destPath, _ := NewPath(copier.Client, dest)
+
+	copyJobs := make(chan CopyJob)
+	go GetCopyJobs(context.Background(), srcPath, destPath, CopyJobsOptions{Recursive: true}, copyJobs)
+
+	changed := make(chan CopyJob)
+	syncErrors := make(chan error, 1)
+	var extraneous []Path
+	go func() {
+		var err error
+		extraneous, err = copier.SyncCopyJobs(context.Background(), destPath, copyJobs, changed)
+		syncErrors <- err
+	}()
+
+	destinations := []string{}
+	for copyJob := range changed {
+		destinations = append(destinations, copyJob.destination.String())
+	}
+
+	if err := <-syncErrors; err != nil {
+		t.Fatalf("encountered error while syncing %s", err)
+	}
+	return destinations, extraneous
+}
+
+func TestSyncCopyJobsSkipsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "src")
+	dest := path.Join(dir, "dest")
+	writeTestFile(t, path.Join(src, "same"), "same")
+	writeTestFile(t, path.Join(src, "resized"), "resized")
+	writeTestFile(t, path.Join(src, "missing"), "missing")
+	writeTestFile(t, path.Join(dest, "same"), "same")
+	writeTestFile(t, path.Join(dest, "resized"), "old")
+
+	copier := NewCopier(CopierOptions{Concurrency: 2}, nil)
+	destinations, _ := syncedDestinations(t, copier, src, dest)
+
+	if len(destinations) != 2 {
+		t.Errorf("expected 2 changed jobs but got %v", destinations)
+	}
+	for _, destination := range destinations {
+		if destination == path.Join(dest, "same") {
+			t.Errorf("expected unchanged destination %s to be skipped", destination)
+		}
+	}
+}
+
+func TestSyncCopyJobsChecksum(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "src")
+	dest := path.Join(dir, "dest")
+	writeTestFile(t, path.Join(dest, "file"), "aaaa")
+	writeTestFile(t, path.Join(src, "file"), "bbbb")
+
+	// The destination is newer and the same size so only the checksum differs
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(path.Join(dest, "file"), future, future)
+
+	copier := NewCopier(CopierOptions{Concurrency: 1}, nil)
+	if destinations, _ := syncedDestinations(t, copier, src, dest); len(destinations) != 0 {
+		t.Errorf("expected no changed jobs without checksums but got %v", destinations)
+	}
+
+	copier = NewCopier(CopierOptions{Checksum: true, Concurrency: 1}, nil)
+	if destinations, _ := syncedDestinations(t, copier, src, dest); len(destinations) != 1 {
+		t.Errorf("expected 1 changed job with checksums but got %v", destinations)
+	}
+}
+
+func TestSyncCopyJobsExtraneous(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "src")
+	dest := path.Join(dir, "dest")
+	writeTestFile(t, path.Join(src, "a", "kept"), "kept")
+	writeTestFile(t, path.Join(dest, "a", "kept"), "kept")
+	writeTestFile(t, path.Join(dest, "a", "stale"), "stale")
+	writeTestFile(t, path.Join(dest, "b", "stale"), "stale")
+
+	copier := NewCopier(CopierOptions{Concurrency: 2}, nil)
+	_, extraneous := syncedDestinations(t, copier, src, dest)
+
+	if len(extraneous) != 2 {
+		t.Fatalf("expected 2 extraneous paths but got %v", extraneous)
+	}
+	if extraneous[0].String() != path.Join(dest, "a", "stale") || extraneous[1].String() != path.Join(dest, "b", "stale") {
+		t.Errorf("expected the stale paths in order but got %v", extraneous)
+	}
+
+	err := copier.DeletePaths(context.Background(), extraneous)
+	if err != nil {
+		t.Fatalf("encountered error while deleting %s", err)
+	}
+	if _, err := os.Stat(path.Join(dest, "a", "stale")); !os.IsNotExist(err) {
+		t.Errorf("expected dest/a/stale to be deleted")
+	}
+	if _, err := os.Stat(path.Join(dest, "a", "kept")); err != nil {
+		t.Errorf("expected dest/a/kept to be kept but got %s", err)
+	}
+}
+
+func TestSyncCopyJobsBucketRoot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<ListBucketResult>
+			<Contents><Key>extra</Key><Size>5</Size><LastModified>2100-01-01T00:00:00Z</LastModified></Contents>
+			<Contents><Key>same</Key><Size>4</Size><LastModified>2100-01-01T00:00:00Z</LastModified></Contents>
+		</ListBucketResult>`))
+	}))
+	defer server.Close()
+
+	client := s3.New(s3.Options{
+		Region:           "us-west-2",
+		Credentials:      aws.AnonymousCredentials{},
+		EndpointResolver: s3.EndpointResolverFromURL(server.URL),
+		UsePathStyle:     true,
+		RetryMaxAttempts: 1,
+	})
+
+	src := path.Join(t.TempDir(), "src")
+	writeTestFile(t, path.Join(src, "same"), "same")
+	writeTestFile(t, path.Join(src, "missing"), "missing")
+
+	copier := NewCopier(CopierOptions{Concurrency: 2}, client)
+	destinations, extraneous := syncedDestinations(t, copier, src, "s3://bucket/")
+
+	if len(destinations) != 1 || destinations[0] != "s3://bucket/missing" {
+		t.Errorf("expected only s3://bucket/missing to be copied but got %v", destinations)
+	}
+	if len(extraneous) != 1 || extraneous[0].String() != "s3://bucket/extra" {
+		t.Errorf("expected s3://bucket/extra to be extraneous but got %v", extraneous)
+	}
+}